@@ -0,0 +1,89 @@
+package ioc
+
+import "reflect"
+
+var typeError = reflect.TypeOf((*error)(nil)).Elem()
+
+// constructor contains the reflected signature of a constructor function.
+type constructor struct {
+	fn      reflect.Value
+	params  []reflect.Type
+	out     reflect.Type
+	returns bool // returns an error as the second result
+}
+
+// newConstructor reflects the signature of a constructor function.
+//
+// Returns an error when:
+//	- fn is nil or isn't a function.
+//	- fn is variadic.
+//	- fn doesn't return a value or a value and an error.
+func newConstructor(fn interface{}, name string) (*constructor, error) {
+	typ := reflect.TypeOf(fn)
+	if typ == nil {
+		return nil, errNilType(name)
+	}
+	if typ.Kind() != reflect.Func || reflect.ValueOf(fn).IsNil() {
+		return nil, errInvalidConstructor(typ, name, "must be a non-nil function")
+	}
+	if typ.IsVariadic() {
+		return nil, errInvalidConstructor(typ, name, "can't be variadic")
+	}
+	switch {
+	case typ.NumOut() == 1 && typ.Out(0) != typeError:
+	case typ.NumOut() == 2 && typ.Out(0) != typeError && typ.Out(1) == typeError:
+	default:
+		return nil, errInvalidConstructor(typ, name, "must return a value or a value and an error")
+	}
+	params := make([]reflect.Type, typ.NumIn())
+	for i := range params {
+		params[i] = typ.In(i)
+	}
+	return &constructor{
+		fn:      reflect.ValueOf(fn),
+		params:  params,
+		out:     typ.Out(0),
+		returns: typ.NumOut() == 2,
+	}, nil
+}
+
+// Get the non-pointer types of the constructor parameters.
+//
+// The dependency types match the types used to register and resolve instances.
+func (ctor *constructor) dependencies() []reflect.Type {
+	dependencies := make([]reflect.Type, len(ctor.params))
+	for i, typ := range ctor.params {
+		dependencies[i] = elemType(typ)
+	}
+	return dependencies
+}
+
+// Get the non-pointer type of the constructor return type.
+func (ctor *constructor) dependencyType() reflect.Type {
+	return elemType(ctor.out)
+}
+
+// elemType dereferences pointer types until a non-pointer type is found.
+func elemType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// createInstance resolves the constructor parameters using the factory and calls the constructor.
+func (ctor *constructor) createInstance(factory Factory) (interface{}, error) {
+	args := make([]reflect.Value, len(ctor.params))
+	for i, typ := range ctor.params {
+		arg := reflect.New(typ)
+		if err := factory.ResolveNamed(arg.Interface(), ""); err != nil {
+			return nil, err
+		}
+		args[i] = arg.Elem()
+	}
+	out := ctor.fn.Call(args)
+	if ctor.returns && !out[1].IsNil() {
+		return nil, out[1].Interface().(error)
+	}
+	return out[0].Interface(), nil
+}
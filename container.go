@@ -1,11 +1,29 @@
 package ioc
 
+import (
+	"context"
+	"reflect"
+)
+
 // Container is an inversion of control container.
 type Container struct {
-	root *Container
+	root   *Container
+	parent *Container
 	*Values
 	r         *registry
 	instances *Values
+	multi     *registrationValues
+	d         *disposables
+	// depth is the depth of the container scope. (0 for the root container)
+	depth int
+	// flight tracks the in-progress constructions of the singleton instances.
+	flight *constructions
+	// pools contains the instance pools of the Pooled registrations made on the container.
+	pools *pools
+	// expiries contains the expiry of the time-bounded instances. (see PerContainerTTL)
+	expiries *expiries
+	// tag is the name of a named scope. (see ScopeNamed)
+	tag string
 }
 
 //-----------------------------------------------
@@ -18,25 +36,89 @@ func NewContainer() *Container {
 		Values:    NewValues(),
 		r:         newRegistry(),
 		instances: NewValues(),
+		multi:     newRegistrationValues(),
+		d:         newDisposables(),
+		flight:    newConstructions(),
+		pools:     newPools(),
+		expiries:  newExpiries(),
+	}
+}
+
+// ScopeOption configures a scoped container.
+type ScopeOption func(*Container)
+
+// OwnPerRequest transfers the ownership of the Per Request instances created through a scoped container to the scope,
+// to dispose the instances when the scope is closed.
+func OwnPerRequest() ScopeOption {
+	return func(c *Container) {
+		c.d.ownPerRequest = true
 	}
 }
 
 // Scope creates a new scoped container from the current container.
 //
-// The Values of the current container are scoped and the registry inherited by the scoped container.
+// The Values and the registry of the current container are scoped.
+// Registrations made on the scoped container are only visible to the scoped container and its descendants,
+// and are discarded when the scoped container is closed.
 //
 // Scoped Values will resolve an instance from an ancestor when the current container is unable to resolve the instance by type and name.
-func (c *Container) Scope() *Container {
+//
+// The scoped container should be closed when it's no longer used, to dispose the instances it owns. (see Close)
+// Open scoped containers owning instances or registrations are closed when the current container is closed,
+// and a scoped container can't resolve instances once an ancestor is closed.
+func (c *Container) Scope(opts ...ScopeOption) *Container {
 	root := c
 	if c.root != nil {
 		root = c.root
 	}
-	return &Container{
+	scope := &Container{
 		root:      root,
+		parent:    c,
+		depth:     c.depth + 1,
 		Values:    NewValuesScope(c.Values),
-		r:         c.r,
+		r:         newRegistryScope(c.r),
 		instances: NewValues(),
+		multi:     newRegistrationValues(),
+		d:         newDisposables(),
+		flight:    newConstructions(),
+		pools:     newPools(),
+		expiries:  newExpiries(),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(scope)
+		}
+	}
+	// the scope is only tracked by the current container once it owns instances or registrations (see attach),
+	// i.e. a scope that isn't closed is garbage collected when it's no longer used
+	return scope
+}
+
+// ScopeNamed creates a new named scoped container from the current container.
+//
+// Instances registered with the PerNamedScope(name) lifetime are cached on the nearest scope with the name,
+// when resolved from the named scope or its descendants. (see Scope)
+func (c *Container) ScopeNamed(name string, opts ...ScopeOption) *Container {
+	scope := c.Scope(opts...)
+	scope.tag = name
+	return scope
+}
+
+// ScopeName returns the name of a named scope, or "" when the container isn't a named scope. (see ScopeNamed)
+func (c *Container) ScopeName() string {
+	return c.tag
+}
+
+// Returns true when the container or an ancestor is closed.
+//
+// Closing a container closes the open child scopes it tracks.
+func (c *Container) isClosed() bool {
+	for x := c; x != nil; x = x.parent {
+		if x.d.isClosed() {
+			return true
+		}
 	}
+	return false
 }
 
 //-----------------------------------------------
@@ -70,7 +152,7 @@ func (c *Container) MustRegister(createInstance func(Factory) (interface{}, erro
 //	- The factory function is nil. (createInstance)
 //	- The implementing type is nil.
 //	- The implementing type isn't a pointer.
-//	- The instance lifetime isn't supported. Currently only PerContainer, PerScope and PerRequest lifetimes are supported.
+//	- The instance lifetime isn't supported. (see RegisterLifetime)
 func (c *Container) RegisterNamed(createInstance func(Factory) (interface{}, error), implType interface{}, name string, lifetime Lifetime) error {
 	typ, err := GetNamedType(implType, name)
 	if err != nil {
@@ -85,12 +167,7 @@ func (c *Container) RegisterNamed(createInstance func(Factory) (interface{}, err
 		CreateInstanceFn: createInstance,
 		Lifetime:         lifetime,
 	}
-	// must keep the Lifetime check in sync with dependencyResolver.ResolveNamed
-	if lifetime != PerContainer && lifetime != PerScope && lifetime != PerRequest {
-		return errUnsupportedLifetime(registration.Type, registration.Name, lifetime)
-	}
-	c.r.set(typ, name, registration)
-	return nil
+	return c.register(registration)
 }
 
 // Register a named instance factory with a specific lifetime.
@@ -102,14 +179,240 @@ func (c *Container) MustRegisterNamed(createInstance func(Factory) (interface{},
 	}
 }
 
-// Register an instance on the root container.
+// Register an instance factory receiving the context of the request with a specific lifetime.
+//
+// RegisterContext calls RegisterNamedContext(createInstance, implType, "", lifetime).
+func (c *Container) RegisterContext(createInstance func(context.Context, Factory) (interface{}, error), implType interface{}, lifetime Lifetime) error {
+	return c.RegisterNamedContext(createInstance, implType, "", lifetime)
+}
+
+// Register an instance factory receiving the context of the request with a specific lifetime.
+//
+// MustRegisterContext calls RegisterContext(createInstance, implType, lifetime) and panics if an error is returned.
+func (c *Container) MustRegisterContext(createInstance func(context.Context, Factory) (interface{}, error), implType interface{}, lifetime Lifetime) {
+	if err := c.RegisterContext(createInstance, implType, lifetime); err != nil {
+		panic(err)
+	}
+}
+
+// Register a named instance factory receiving the context of the request with a specific lifetime.
+//
+// The context is the context passed to ResolveNamedContext, or context.Background()
+// when the instance is resolved without a context. (see ContextFactory)
+//
+// Returns an error when the instance factory can't be registered. (see RegisterNamed)
+func (c *Container) RegisterNamedContext(createInstance func(context.Context, Factory) (interface{}, error), implType interface{}, name string, lifetime Lifetime) error {
+	var fn func(Factory) (interface{}, error)
+	if createInstance != nil {
+		fn = func(factory Factory) (interface{}, error) {
+			ctx := context.Background()
+			if contextFactory, ok := factory.(ContextFactory); ok {
+				ctx = contextFactory.Context()
+			}
+			return createInstance(ctx, factory)
+		}
+	}
+	return c.RegisterNamed(fn, implType, name, lifetime)
+}
+
+// Register a named instance factory receiving the context of the request with a specific lifetime.
+//
+// MustRegisterNamedContext calls RegisterNamedContext(createInstance, implType, name, lifetime) and panics if an error is returned.
+func (c *Container) MustRegisterNamedContext(createInstance func(context.Context, Factory) (interface{}, error), implType interface{}, name string, lifetime Lifetime) {
+	if err := c.RegisterNamedContext(createInstance, implType, name, lifetime); err != nil {
+		panic(err)
+	}
+}
+
+// Register a constructor function with a specific lifetime.
+//
+// The constructor parameters are resolved by type using the Factory passed to (*Registration).CreateInstance
+// and are recorded as the registration Dependencies.
+//
+// The constructor must return a value or a value and an error, e.g.
+//	func(db *sql.DB, log Logger) (*PostgresUserRepository, error)
+//
+// The implementing type is the constructor return type, unless set with the As option.
+// The name is set with the WithName option. The lifetime is set by the lifetime argument.
+//
+// Returns an error when:
+//	- The constructor is nil, isn't a function, is variadic or has an unsupported signature.
+//	- The WithLifetime option is passed.
+//	- The implementing type isn't a pointer.
+//	- The constructor return type doesn't match or implement the implementing type.
+//	- The instance lifetime isn't supported. (see RegisterLifetime)
+func (c *Container) RegisterConstructor(fn interface{}, lifetime Lifetime, opts ...RegisterOption) error {
+	options := newRegisterOptions(opts)
+	ctor, err := newConstructor(fn, options.name)
+	if err != nil {
+		return err
+	}
+	if options.lifetimeSet {
+		return errInvalidConstructor(reflect.TypeOf(fn), options.name, "can't be registered using the WithLifetime option (use the lifetime argument)")
+	}
+	implType := options.implType
+	if implType == nil {
+		implType = reflect.New(ctor.out).Interface()
+	}
+	typ, err := GetNamedType(implType, options.name)
+	if err != nil {
+		return err
+	}
+	if typ.Kind() == reflect.Interface {
+		if !ctor.out.Implements(typ) {
+			return errInterfaceNotImplemented(ctor.out, options.name, typ)
+		}
+	} else if out := ctor.dependencyType(); out != typ {
+		return errUnexpectedValueType(out, options.name, typ)
+	}
+	registration := &Registration{
+		Type:             typ,
+		Name:             options.name,
+		CreateInstanceFn: ctor.createInstance,
+		Lifetime:         lifetime,
+		Dependencies:     ctor.dependencies(),
+	}
+	return c.register(registration)
+}
+
+// Register a constructor function with a specific lifetime.
+//
+// MustRegisterConstructor calls RegisterConstructor(fn, lifetime, opts...) and panics if an error is returned.
+func (c *Container) MustRegisterConstructor(fn interface{}, lifetime Lifetime, opts ...RegisterOption) {
+	if err := c.RegisterConstructor(fn, lifetime, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// Add an instance factory with a specific lifetime as one of many registrations for the implementing type.
+//
+// Add calls AddNamed(createInstance, implType, "", lifetime).
+func (c *Container) Add(createInstance func(Factory) (interface{}, error), implType interface{}, lifetime Lifetime) error {
+	return c.AddNamed(createInstance, implType, "", lifetime)
+}
+
+// Add an instance factory with a specific lifetime as one of many registrations for the implementing type.
+//
+// MustAdd calls Add(createInstance, implType, lifetime) and panics if an error is returned.
+func (c *Container) MustAdd(createInstance func(Factory) (interface{}, error), implType interface{}, lifetime Lifetime) {
+	if err := c.Add(createInstance, implType, lifetime); err != nil {
+		panic(err)
+	}
+}
+
+// Add a named instance factory with a specific lifetime as one of many registrations for the implementing type and name.
+//
+// Multi registrations don't override each other and are resolved in the order they were added using ResolveAll/ResolveAllNamed.
+// ResolveNamed resolves the last added multi registration, unless a registration was set using Register/RegisterNamed.
+//
+// Returns an error when:
+//	- The factory function is nil. (createInstance)
+//	- The implementing type is nil.
+//	- The implementing type isn't a pointer.
+//	- The instance lifetime isn't supported. (see RegisterLifetime)
+func (c *Container) AddNamed(createInstance func(Factory) (interface{}, error), implType interface{}, name string, lifetime Lifetime) error {
+	typ, err := GetNamedType(implType, name)
+	if err != nil {
+		return err
+	}
+	if createInstance == nil {
+		return errCreateInstanceFnNil(typ, name)
+	}
+	registration := &Registration{
+		Type:             typ,
+		Name:             name,
+		CreateInstanceFn: createInstance,
+		Lifetime:         lifetime,
+		Multi:            true,
+	}
+	return c.register(registration)
+}
+
+// Add a named instance factory with a specific lifetime as one of many registrations for the implementing type and name.
+//
+// MustAddNamed calls AddNamed(createInstance, implType, name, lifetime) and panics if an error is returned.
+func (c *Container) MustAddNamed(createInstance func(Factory) (interface{}, error), implType interface{}, name string, lifetime Lifetime) {
+	if err := c.AddNamed(createInstance, implType, name, lifetime); err != nil {
+		panic(err)
+	}
+}
+
+// Decorate the instances created for a type.
+//
+// Decorate calls DecorateNamed(decorate, implType, "").
+func (c *Container) Decorate(decorate func(Factory, interface{}) (interface{}, error), implType interface{}) error {
+	return c.DecorateNamed(decorate, implType, "")
+}
+
+// Decorate the instances created for a type.
+//
+// MustDecorate calls Decorate(decorate, implType) and panics if an error is returned.
+func (c *Container) MustDecorate(decorate func(Factory, interface{}) (interface{}, error), implType interface{}) {
+	if err := c.Decorate(decorate, implType); err != nil {
+		panic(err)
+	}
+}
+
+// Decorate the instances created for a type and name.
+//
+// After (*Registration).CreateInstanceFn creates an instance, the decorators for the type and name
+// are called in the order they were added to wrap the instance, before the instance is cached according to the lifetime.
+//
+// Decorators apply to the registrations and multi registrations for the type and name, including
+// registrations made after the decorator was added. Instances registered using RegisterInstance/RegisterNamedInstance
+// and instances created before the decorator was added aren't decorated.
+//
+// Returns an error when:
+//	- The decorator function is nil.
+//	- The implementing type is nil.
+//	- The implementing type isn't a pointer.
+func (c *Container) DecorateNamed(decorate func(Factory, interface{}) (interface{}, error), implType interface{}, name string) error {
+	typ, err := GetNamedType(implType, name)
+	if err != nil {
+		return err
+	}
+	if decorate == nil {
+		return errDecorateFnNil(typ, name)
+	}
+	c.attach()
+	c.r.decorate(typ, name, decorate)
+	return nil
+}
+
+// Decorate the instances created for a type and name.
+//
+// MustDecorateNamed calls DecorateNamed(decorate, implType, name) and panics if an error is returned.
+func (c *Container) MustDecorateNamed(decorate func(Factory, interface{}) (interface{}, error), implType interface{}, name string) {
+	if err := c.DecorateNamed(decorate, implType, name); err != nil {
+		panic(err)
+	}
+}
+
+// register adds or updates a registration after checking the lifetime.
+func (c *Container) register(registration *Registration) error {
+	if getLifetimeManager(registration.Lifetime) == nil {
+		return errUnsupportedLifetime(registration.Type, registration.Name, registration.Lifetime)
+	}
+	if c.root != nil {
+		registration.scope = c
+		c.attach()
+	}
+	if registration.Multi {
+		c.r.add(registration.Type, registration.Name, registration)
+	} else {
+		c.r.set(registration.Type, registration.Name, registration)
+	}
+	return nil
+}
+
+// Register an instance on the container.
 //
 // RegisterInstance calls RegisterNamedInstance(v, "").
 func (c *Container) RegisterInstance(v interface{}) error {
 	return c.RegisterNamedInstance(v, "")
 }
 
-// Register an instance on the root container.
+// Register an instance on the container.
 //
 // MustRegisterInstance calls RegisterInstance(v) and panics if an error is returned.
 func (c *Container) MustRegisterInstance(v interface{}) {
@@ -118,7 +421,9 @@ func (c *Container) MustRegisterInstance(v interface{}) {
 	}
 }
 
-// Register a named instance on the root container.
+// Register a named instance on the container.
+//
+// An instance registered on a scoped container is only visible to the scoped container and its descendants. (see Scope)
 //
 // Returns an error when:
 //	- The instance type is nil.
@@ -139,16 +444,16 @@ func (c *Container) RegisterNamedInstance(v interface{}, name string) error {
 		CreateInstanceFn: createInstance,
 		Lifetime:         PerContainer,
 	}
-	c.r.set(typ, name, registration)
-	root := c.root
-	if root == nil {
-		root = c
+	if c.root != nil {
+		registration.scope = c
+		c.attach()
 	}
-	root.instances.set(typ, name, instance)
+	c.r.set(typ, name, registration)
+	c.instances.set(typ, name, instance)
 	return nil
 }
 
-// Register a named instance on the root container.
+// Register a named instance on the container.
 //
 // MustRegisterNamedInstance calls RegisterNamedInstance(v, name) and panics if an error is returned.
 func (c *Container) MustRegisterNamedInstance(v interface{}, name string) {
@@ -157,6 +462,39 @@ func (c *Container) MustRegisterNamedInstance(v interface{}, name string) {
 	}
 }
 
+// Validate ensures the dependencies of every registration can be resolved by the container.
+//
+// Only registrations with known Dependencies (registered using RegisterConstructor) are validated.
+//
+// A provider dependency, e.g. Lazy[T] or func() (T, error), is valid when the provided type T can be resolved.
+//
+// Returns an error when a dependency isn't registered and can't be found on the container Values.
+func (c *Container) Validate() error {
+	for _, registration := range c.Registrations() {
+		for _, typ := range registration.Dependencies {
+			if !c.canResolve(typ) {
+				return errMissingDependency(typ, "", registration.Type, registration.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// canResolve returns true when a dependency type is registered, found on the container Values
+// or a provider for a type that can be resolved.
+func (c *Container) canResolve(typ reflect.Type) bool {
+	if typ == typeContainer || typ == typeFactory {
+		return true
+	}
+	if c.r.get(typ, "") != nil || c.get(typ, "") != nil {
+		return true
+	}
+	if provided := providedType(typ); provided != nil {
+		return c.canResolve(provided)
+	}
+	return false
+}
+
 //-----------------------------------------------
 // factory implementation
 //-----------------------------------------------
@@ -182,21 +520,55 @@ func (c *Container) MustResolve(v interface{}) {
 // ResolveNamed creates a dependency resolver implementing the Factory interface, that proxies resolve calls to the Container.
 //
 // The dependency resolver is passed to instance factory functions (instead of the container) and keeps track
-// of the resolve call history for the request to detect infinite recursion.
+// of the registrations being created for the request to detect infinite recursion.
 //
 // Returns an error when:
 //	- The value type is nil.
 //	- The value isn't a pointer.
 //	- The value is a nil pointer e.g. (*string)(nil) (use a pointer to a (nil) pointer instead)
 //	- The dependency can't be resolved (not registered).
-//	- The instance lifetime isn't supported. Currently only PerContainer, PerScope and PerRequest lifetimes are supported.
+//	- The instance lifetime isn't supported. (see RegisterLifetime)
 //	- An error was returned when (*Registration).CreateInstance was called.
-//	- Infinite recursion is detected on a repetitive call to resolve an instance by type and name.
+//	- Infinite recursion is detected when an instance is resolved while it's being created. (a dependency cycle)
 func (c *Container) ResolveNamed(v interface{}, name string) error {
-	resolver := newDependencyResolver(c, newDependencyResolverGraph())
+	return c.ResolveNamedContext(context.Background(), v, name)
+}
+
+// Resolve a named instance by type using a context.
+//
+// The context is passed to the factory functions of the instances created for the request (see ContextFactory)
+// and aborts waiting on the in-progress construction of a singleton instance when the context is done.
+//
+// Returns an error when:
+//	- An instance can't be resolved. (see ResolveNamed)
+//	- The context is done. The error wraps ctx.Err().
+func (c *Container) ResolveNamedContext(ctx context.Context, v interface{}, name string) error {
+	resolver := newDependencyResolver(ctx, c, newDependencyResolverGraph())
+	defer resolver.g.finish()
 	return resolver.ResolveNamed(v, name)
 }
 
+// WithResolution calls fn with a Factory resolving instances from the container within one resolution, using the context.
+//
+// The Per Resolve instances are shared by the resolve calls of fn, like the dependencies of one instance. (see PerResolve)
+// The Factory must not be used after fn returns.
+//
+// Returns the error returned by fn.
+func (c *Container) WithResolution(ctx context.Context, fn func(factory Factory) error) error {
+	resolver := newDependencyResolver(ctx, c, newDependencyResolverGraph())
+	defer resolver.g.finish()
+	return fn(resolver)
+}
+
+// Resolve a named instance by type using a context.
+//
+// MustResolveNamedContext calls ResolveNamedContext and panics if an error is returned.
+func (c *Container) MustResolveNamedContext(ctx context.Context, v interface{}, name string) {
+	if err := c.ResolveNamedContext(ctx, v, name); err != nil {
+		panic(err)
+	}
+}
+
 // Resolve a named instance by type.
 //
 // MustResolveNamed calls ResolveNamed and panics if an error is returned.
@@ -205,3 +577,101 @@ func (c *Container) MustResolveNamed(v interface{}, name string) {
 		panic(err)
 	}
 }
+
+// Resolve an optional named instance by type.
+//
+// ResolveNamedOptional returns false and no error when the instance isn't registered, can't be found
+// on the container Values and isn't a provider type. Errors raised while creating a registered instance are returned.
+//
+// Returns an error when:
+//	- The value type is nil.
+//	- The value isn't a pointer.
+//	- The value is a nil pointer e.g. (*string)(nil) (use a pointer to a (nil) pointer instead)
+//	- The instance lifetime isn't supported. (see RegisterLifetime)
+//	- An error was returned when (*Registration).CreateInstance was called.
+//	- Infinite recursion is detected when an instance is resolved while it's being created. (a dependency cycle)
+func (c *Container) ResolveNamedOptional(v interface{}, name string) (bool, error) {
+	return c.resolveNamedOptionalContext(context.Background(), v, name)
+}
+
+// Resolve an optional named instance by type using a context. (see ResolveNamedOptional and ResolveNamedContext)
+func (c *Container) resolveNamedOptionalContext(ctx context.Context, v interface{}, name string) (bool, error) {
+	resolver := newDependencyResolver(ctx, c, newDependencyResolverGraph())
+	defer resolver.g.finish()
+	return resolver.ResolveNamedOptional(v, name)
+}
+
+// Resolve every instance added for a type.
+//
+// ResolveAll calls c.ResolveAllNamed(v, "").
+func (c *Container) ResolveAll(v interface{}) error {
+	return c.ResolveAllNamed(v, "")
+}
+
+// Resolve every instance added for a type.
+//
+// MustResolveAll calls ResolveAll(v) and panics if an error is returned.
+func (c *Container) MustResolveAll(v interface{}) {
+	if err := c.ResolveAll(v); err != nil {
+		panic(err)
+	}
+}
+
+// Resolve every instance added for a type and name.
+//
+// ResolveAllNamed sets v, a pointer to a slice, to the instances of the multi registrations
+// for the slice element type and name, in the order they were added. (see AddNamed)
+//
+// Returns an error when:
+//	- The value type is nil.
+//	- The value isn't a pointer to a slice.
+//	- An instance can't be resolved. (see ResolveNamed)
+func (c *Container) ResolveAllNamed(v interface{}, name string) error {
+	return c.resolveAllNamedContext(context.Background(), v, name)
+}
+
+// Resolve every instance added for a type and name using a context. (see ResolveAllNamed and ResolveNamedContext)
+func (c *Container) resolveAllNamedContext(ctx context.Context, v interface{}, name string) error {
+	resolver := newDependencyResolver(ctx, c, newDependencyResolverGraph())
+	defer resolver.g.finish()
+	return resolver.ResolveAllNamed(v, name)
+}
+
+// Resolve every instance added for a type and name.
+//
+// MustResolveAllNamed calls ResolveAllNamed(v, name) and panics if an error is returned.
+func (c *Container) MustResolveAllNamed(v interface{}, name string) {
+	if err := c.ResolveAllNamed(v, name); err != nil {
+		panic(err)
+	}
+}
+
+// Resolve every named instance of a type.
+//
+// ResolveNamedMap sets v, a pointer to a map with a string key type, to every named instance of the map element type.
+// The names of the registrations and of the instances on the container Values are resolved
+// using the same rules as ResolveNamed.
+//
+// Returns an error when:
+//	- The value type is nil.
+//	- The value isn't a pointer to a map with a string key type.
+//	- An instance can't be resolved. (see ResolveNamed)
+func (c *Container) ResolveNamedMap(v interface{}) error {
+	return c.resolveNamedMapContext(context.Background(), v)
+}
+
+// Resolve every named instance of a type using a context. (see ResolveNamedMap and ResolveNamedContext)
+func (c *Container) resolveNamedMapContext(ctx context.Context, v interface{}) error {
+	resolver := newDependencyResolver(ctx, c, newDependencyResolverGraph())
+	defer resolver.g.finish()
+	return resolver.ResolveNamedMap(v)
+}
+
+// Resolve every named instance of a type.
+//
+// MustResolveNamedMap calls ResolveNamedMap(v) and panics if an error is returned.
+func (c *Container) MustResolveNamedMap(v interface{}) {
+	if err := c.ResolveNamedMap(v); err != nil {
+		panic(err)
+	}
+}
@@ -1,8 +1,15 @@
 package ioc
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -15,6 +22,7 @@ import (
 // Scope()
 // - resolve lifetime (per root container, per [scoped] container, per request)
 // - values must be scoped
+// - registrations must be scoped (registry overlay)
 // Supported lifetimes (PerContainer, PerScope, PerRequest)
 
 var _ = Describe("Container", func() {
@@ -27,12 +35,12 @@ var _ = Describe("Container", func() {
 	})
 
 	basicSingletonTests := func(lifetime Lifetime) {
-		// when RegisterInstance is used on the root or a scoped container,
-		// the instance is always stored on the root container
+		// when RegisterInstance is used on a scoped container,
+		// the instance is stored on the scoped container
 		// see:
 		// if lifetime != PerScope { return }
-		// ensure the value is available from the root container,
-		// even though it was registered on a scoped container.
+		// ensure the value isn't available from the root container,
+		// because it was registered on a scoped container.
 		It("should register/resolve simple singletons", func() {
 			// int
 			container.MustRegisterInstance(1)
@@ -47,12 +55,8 @@ var _ = Describe("Container", func() {
 			if lifetime != PerScope {
 				return
 			}
-			vint = 0
-			rootContainer.MustResolve(&vint)
-			Expect(vint).To(Equal(1))
-			vstr = ""
-			rootContainer.MustResolve(&vstr)
-			Expect(vstr).To(Equal("test"))
+			Expect(rootContainer.Resolve(&vint)).To(MatchError(ErrNotFound))
+			Expect(rootContainer.Resolve(&vstr)).To(MatchError(ErrNotFound))
 		})
 		It("should register/resolve struct singletons", func() {
 			type V struct{ name string }
@@ -63,9 +67,7 @@ var _ = Describe("Container", func() {
 			if lifetime != PerScope {
 				return
 			}
-			v = V{}
-			rootContainer.MustResolve(&v)
-			Expect(v.name).To(Equal("test"))
+			Expect(rootContainer.Resolve(&v)).To(MatchError(ErrNotFound))
 		})
 		It("should register/resolve multiple anonymous struct singletons "+
 			"to show that anonymous struct types are unique", func() {
@@ -80,12 +82,8 @@ var _ = Describe("Container", func() {
 			if lifetime != PerScope {
 				return
 			}
-			v1 = struct{ name string }{}
-			rootContainer.MustResolve(&v1)
-			Expect(v1.name).To(Equal("test"))
-			v2 = struct{ count int }{}
-			rootContainer.MustResolve(&v2)
-			Expect(v2.count).To(Equal(1))
+			Expect(rootContainer.Resolve(&v1)).To(MatchError(ErrNotFound))
+			Expect(rootContainer.Resolve(&v2)).To(MatchError(ErrNotFound))
 		})
 		It("should register/resolve interface singletons", func() {
 			type IN interface{}
@@ -103,12 +101,7 @@ var _ = Describe("Container", func() {
 			if lifetime != PerScope {
 				return
 			}
-			v = nil
-			rootContainer.MustResolve(&v)
-			Expect(v).ToNot(BeNil())
-			v1, ok = v.(int)
-			Expect(ok).To(BeTrue())
-			Expect(v1).To(Equal(1))
+			Expect(rootContainer.Resolve(&v)).To(MatchError(ErrNotFound))
 		})
 		It("should register/resolve named singletons", func() {
 			container.MustRegisterNamedInstance(1, "one")
@@ -121,11 +114,8 @@ var _ = Describe("Container", func() {
 			if lifetime != PerScope {
 				return
 			}
-			one, two = 0, 0
-			rootContainer.MustResolveNamed(&one, "one")
-			rootContainer.MustResolveNamed(&two, "two")
-			Expect(one).To(Equal(1))
-			Expect(two).To(Equal(2))
+			Expect(rootContainer.ResolveNamed(&one, "one")).To(MatchError(ErrNotFound))
+			Expect(rootContainer.ResolveNamed(&two, "two")).To(MatchError(ErrNotFound))
 		})
 		It("should override the last singleton set", func() {
 			container.MustRegisterInstance(1)
@@ -136,9 +126,7 @@ var _ = Describe("Container", func() {
 			if lifetime != PerScope {
 				return
 			}
-			v = 0
-			rootContainer.MustResolve(&v)
-			Expect(v).To(Equal(2))
+			Expect(rootContainer.Resolve(&v)).To(MatchError(ErrNotFound))
 		})
 		It("should override the last named singleton set", func() {
 			container.MustRegisterNamedInstance(1, "one")
@@ -149,15 +137,13 @@ var _ = Describe("Container", func() {
 			if lifetime != PerScope {
 				return
 			}
-			v = 0
-			rootContainer.MustResolveNamed(&v, "one")
-			Expect(v).To(Equal(2))
+			Expect(rootContainer.ResolveNamed(&v, "one")).To(MatchError(ErrNotFound))
 		})
 		Context("should return an error when", func() {
 			It("instance not registered", func() {
 				var v int
 				err := container.ResolveNamed(&v, "")
-				Expect(err).ToNot(BeNil())
+				Expect(errors.Is(err, ErrNotFound)).To(BeTrue())
 			})
 		})
 	}
@@ -254,9 +240,8 @@ var _ = Describe("Container", func() {
 					x = 2
 					container.MustResolveNamed(&v, "") // same scope
 					Expect(v).To(Equal(1))
-					x = 2
-					rootContainer.MustResolveNamed(&v, "") // different scope
-					Expect(v).To(Equal(2))
+					// the registration made on the scoped container isn't visible to the root container
+					Expect(rootContainer.ResolveNamed(&v, "")).To(MatchError(ErrNotFound))
 					x = 3
 					scopedContainer := container.Scope()
 					scopedContainer.MustResolveNamed(&v, "") // different scope
@@ -275,24 +260,24 @@ var _ = Describe("Container", func() {
 		Context("should return an error when", func() {
 			It("instance factory is nil", func() {
 				err := container.RegisterNamed(nil, (*string)(nil), "", lifetime)
-				Expect(err).ToNot(BeNil())
+				Expect(errors.Is(err, ErrCreateInstanceNil)).To(BeTrue())
 			})
 			It("instance factory not registered", func() {
 				var v int
 				err := container.ResolveNamed(&v, "")
-				Expect(err).ToNot(BeNil())
+				Expect(errors.Is(err, ErrUnresolvedDependency)).To(BeTrue())
 			})
 			It("instance factory returns wrong value type", func() {
 				container.MustRegisterNamed(func(factory Factory) (interface{}, error) { return "wrong", nil }, (*int)(nil), "", lifetime)
 				var v int
 				err := container.ResolveNamed(&v, "")
-				Expect(err).ToNot(BeNil())
+				Expect(errors.Is(err, ErrUnexpectedValueType)).To(BeTrue())
 			})
 			It("instance factory returns value that doesn't implement interface", func() {
 				container.MustRegisterNamed(func(factory Factory) (interface{}, error) { return "wrong", nil }, (*io.Reader)(nil), "", lifetime)
 				var v io.Reader
 				err := container.ResolveNamed(&v, "")
-				Expect(err).ToNot(BeNil())
+				Expect(errors.Is(err, ErrInterfaceNotImplemented)).To(BeTrue())
 			})
 			It("infinite recursion is detected", func() {
 				container.MustRegisterNamed(func(factory Factory) (interface{}, error) {
@@ -304,15 +289,17 @@ var _ = Describe("Container", func() {
 				}, (*int)(nil), "", lifetime)
 				var v int
 				err := container.ResolveNamed(&v, "")
-				Expect(err).ToNot(BeNil())
+				Expect(errors.Is(err, ErrCycle)).To(BeTrue())
 			})
 			It("an error was returned when (*Registration).CreateInstance was called.", func() {
+				errSomethingWentWrong := fmt.Errorf("Something went wrong")
 				container.MustRegisterNamed(func(factory Factory) (interface{}, error) {
-					return nil, fmt.Errorf("Something went wrong")
+					return nil, errSomethingWentWrong
 				}, (*int)(nil), "", lifetime)
 				var v int
 				err := container.ResolveNamed(&v, "")
-				Expect(err).ToNot(BeNil())
+				Expect(errors.Is(err, ErrCreateInstance)).To(BeTrue())
+				Expect(errors.Is(err, errSomethingWentWrong)).To(BeTrue())
 			})
 		})
 	}
@@ -335,6 +322,677 @@ var _ = Describe("Container", func() {
 		Context("factory function instances", func() { basicFactoryTests(PerRequest) })
 	})
 
+	Context("infinite recursion", func() {
+		type A struct{}
+		type B struct{}
+		type C struct{}
+		It("should report the dependency cycle", func() {
+			container.MustRegisterConstructor(func(*B) *A { return &A{} }, PerRequest)
+			container.MustRegisterConstructor(func(*C) *B { return &B{} }, PerContainer)
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				var a *A
+				if err := factory.ResolveNamed(&a, ""); err != nil {
+					return nil, err
+				}
+				return &C{}, nil
+			}, (*C)(nil), PerRequest)
+			var a *A
+			err := container.Resolve(&a)
+			Expect(errors.Is(err, ErrCycle)).To(BeTrue())
+			Expect(err.(*Error).Code).To(Equal(ErrCreateInstance))
+			Expect(err.Error()).To(ContainSubstring("(ioc.A -> ioc.B -> ioc.C -> ioc.A)"))
+		})
+		It("should resolve the same per request registration any number of times", func() {
+			container.MustRegister(func(Factory) (interface{}, error) { return &A{}, nil }, (*A)(nil), PerRequest)
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				for i := 0; i < 100; i++ {
+					var a *A
+					if err := factory.ResolveNamed(&a, ""); err != nil {
+						return nil, err
+					}
+				}
+				return &B{}, nil
+			}, (*B)(nil), PerRequest)
+			var b *B
+			Expect(container.Resolve(&b)).To(BeNil())
+		})
+	})
+
+	Context("resolve errors", func() {
+		type Service struct{}
+		type Repository interface{}
+		type DB struct{}
+		It("should report the resolution path", func() {
+			container.MustRegisterConstructor(func(*DB) Repository { return 1 }, PerScope, WithName("primary"))
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				var r Repository
+				if err := factory.ResolveNamed(&r, "primary"); err != nil {
+					return nil, err
+				}
+				return &Service{}, nil
+			}, (*Service)(nil), PerRequest)
+			var v *Service
+			err := container.Scope().Resolve(&v)
+			Expect(errors.Is(err, ErrNotFound)).To(BeTrue())
+			Expect(err.Error()).To(HavePrefix(`ioc.Service -> ioc.Repository("primary") -> ioc.DB: `))
+			path := err.(*Error).Path
+			Expect(path).To(HaveLen(3))
+			Expect(path[0].Lifetime).To(Equal(PerRequest))
+			Expect(path[1].Name).To(Equal("primary"))
+			Expect(path[1].Lifetime).To(Equal(PerScope))
+			Expect(path[1].Scope).To(Equal(1))
+			Expect(path[2].Registered).To(BeFalse())
+		})
+		It("should suggest near matches", func() {
+			type Impl struct{ io.Reader }
+			container.MustRegisterNamedInstance(&DB{}, "primary")
+			container.MustRegister(func(Factory) (interface{}, error) { return &Impl{}, nil }, (*Impl)(nil), PerContainer)
+			var db *DB
+			err := container.Resolve(&db)
+			Expect(err).To(MatchError(ErrNotFound))
+			Expect(err.(*Error).Suggestions).To(Equal([]Suggestion{
+				{Type: reflect.TypeOf(DB{}), Name: "primary", Reason: "registered under another name"},
+			}))
+			Expect(err.Error()).To(ContainSubstring(`did you mean ioc.DB("primary") (registered under another name)?`))
+			var r io.Reader
+			err = container.Resolve(&r)
+			Expect(err).To(MatchError(ErrNotFound))
+			Expect(err.(*Error).Suggestions).To(HaveLen(1))
+			Expect(err.(*Error).Suggestions[0].Type).To(Equal(reflect.TypeOf(Impl{})))
+			values := NewValues()
+			var f io.Writer = &bytes.Buffer{}
+			values.MustSet(f)
+			var w io.Writer
+			err = values.Get(&w)
+			Expect(err).To(MatchError(ErrInstanceNotFound))
+			Expect(err.Error()).To(ContainSubstring(`did you mean *bytes.Buffer (implements io.Writer)?`))
+		})
+		It("should match the error codes and the inner error", func() {
+			errSomethingWentWrong := fmt.Errorf("Something went wrong")
+			container.MustRegister(func(Factory) (interface{}, error) {
+				return nil, fmt.Errorf("wrapped: %w", errSomethingWentWrong)
+			}, (*Service)(nil), PerContainer)
+			var v *Service
+			err := container.Resolve(&v)
+			Expect(err).To(MatchError(ErrCreateInstance))
+			Expect(err).ToNot(MatchError(ErrNotFound))
+			Expect(errors.Is(err, errSomethingWentWrong)).To(BeTrue())
+			var e *Error
+			Expect(errors.As(fmt.Errorf("wrapped: %w", err), &e)).To(BeTrue())
+			Expect(e.Code).To(Equal(ErrCreateInstance))
+			Expect(e.Code.String()).To(Equal("ErrCreateInstance"))
+			Expect(ErrNotFound.String()).To(Equal("ErrNotFound"))
+		})
+	})
+
+	Context("context resolution", func() {
+		type contextKey struct{}
+		It("should pass the context to the factory functions", func() {
+			container.MustRegisterContext(func(ctx context.Context, factory Factory) (interface{}, error) {
+				return ctx.Value(contextKey{}).(string), nil
+			}, (*string)(nil), PerRequest)
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				var v string
+				if err := factory.ResolveNamed(&v, ""); err != nil {
+					return nil, err
+				}
+				return len(v), nil
+			}, (*int)(nil), PerRequest)
+			ctx := context.WithValue(context.Background(), contextKey{}, "value")
+			var v int
+			container.MustResolveNamedContext(ctx, &v, "")
+			Expect(v).To(Equal(5))
+		})
+		It("should pass context.Background() when resolved without a context", func() {
+			container.MustRegisterContext(func(ctx context.Context, factory Factory) (interface{}, error) {
+				Expect(ctx).To(Equal(context.Background()))
+				return "", nil
+			}, (*string)(nil), PerRequest)
+			var v string
+			container.MustResolve(&v)
+		})
+		Context("should return an error when", func() {
+			It("the context is done", func() {
+				container.MustRegister(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerRequest)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				var v int
+				err := container.ResolveNamedContext(ctx, &v, "")
+				Expect(err).To(MatchError(ErrContextDone))
+				Expect(err).To(MatchError(context.Canceled))
+			})
+			It("the context is done while waiting on an in-progress construction", func() {
+				started, release := make(chan struct{}), make(chan struct{})
+				container.MustRegister(func(Factory) (interface{}, error) {
+					close(started)
+					<-release
+					return &testStruct{name: "test"}, nil
+				}, (*testStruct)(nil), PerContainer)
+				go func() {
+					defer GinkgoRecover()
+					var v *testStruct
+					container.MustResolve(&v)
+				}()
+				<-started
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				var v *testStruct
+				err := container.ResolveNamedContext(ctx, &v, "")
+				Expect(err).To(MatchError(ErrContextDone))
+				Expect(err).To(MatchError(context.DeadlineExceeded))
+				close(release)
+				Eventually(func() error { return container.Resolve(&v) }).Should(Succeed())
+				Expect(v.name).To(Equal("test"))
+			})
+		})
+	})
+
+	Context("concurrent singleton construction", func() {
+		// resolveConcurrently releases the factory function once every other resolve call waits on the construction
+		resolveConcurrently := func(n int, release chan struct{}) []error {
+			errs := make([]error, n)
+			var wg sync.WaitGroup
+			wg.Add(n)
+			for i := 0; i < n; i++ {
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					var v *testStruct
+					errs[i] = container.Resolve(&v)
+				}(i)
+			}
+			registration := container.r.get(reflect.TypeOf(testStruct{}), "")
+			Eventually(func() int { return container.flight.waiting(registration) }).Should(Equal(n - 1))
+			close(release)
+			wg.Wait()
+			return errs
+		}
+		It("should call the factory function once", func() {
+			var calls int32
+			release := make(chan struct{})
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return &testStruct{name: "test"}, nil
+			}, (*testStruct)(nil), PerContainer)
+			for _, err := range resolveConcurrently(10, release) {
+				Expect(err).To(BeNil())
+			}
+			Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+		})
+		It("should report a failure to every waiter and not cache the failure", func() {
+			var calls int32
+			release := make(chan struct{})
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					<-release
+					return nil, fmt.Errorf("Something went wrong")
+				}
+				return &testStruct{name: "test"}, nil
+			}, (*testStruct)(nil), PerContainer)
+			for _, err := range resolveConcurrently(10, release) {
+				Expect(err).ToNot(BeNil())
+			}
+			Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+			var v *testStruct
+			container.MustResolve(&v)
+			Expect(v.name).To(Equal("test"))
+		})
+		It("should report a panic to every waiter and propagate the panic", func() {
+			release := make(chan struct{})
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				<-release
+				panic("Something went wrong")
+			}, (*testStruct)(nil), PerContainer)
+			type result struct {
+				err       error
+				recovered interface{}
+			}
+			results := make(chan result, 2)
+			for i := 0; i < 2; i++ {
+				go func() {
+					var r result
+					defer func() {
+						r.recovered = recover()
+						results <- r
+					}()
+					var v *testStruct
+					r.err = container.Resolve(&v)
+				}()
+			}
+			registration := container.r.get(reflect.TypeOf(testStruct{}), "")
+			Eventually(func() int { return container.flight.waiting(registration) }).Should(Equal(1))
+			close(release)
+			var err error
+			var recovered interface{}
+			for i := 0; i < 2; i++ {
+				r := <-results
+				if r.recovered != nil {
+					recovered = r.recovered
+				} else {
+					err = r.err
+				}
+			}
+			Expect(recovered).To(Equal("Something went wrong"))
+			Expect(err).To(MatchError(ErrCreateInstance))
+			Expect(err.Error()).To(ContainSubstring("panic: Something went wrong"))
+		})
+		It("should report a dependency cycle between concurrent constructions", func() {
+			type A struct{}
+			type B struct{}
+			entered := make(chan struct{}, 2)
+			release := make(chan struct{})
+			// each factory function resolves its dependency once both constructions are in progress
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				entered <- struct{}{}
+				<-release
+				var b *B
+				if err := Resolve(factory, &b); err != nil {
+					return nil, err
+				}
+				return &A{}, nil
+			}, (*A)(nil), PerContainer)
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				entered <- struct{}{}
+				<-release
+				var a *A
+				if err := Resolve(factory, &a); err != nil {
+					return nil, err
+				}
+				return &B{}, nil
+			}, (*B)(nil), PerContainer)
+			errs := make(chan error, 2)
+			go func() {
+				var a *A
+				errs <- container.Resolve(&a)
+			}()
+			go func() {
+				var b *B
+				errs <- container.Resolve(&b)
+			}()
+			<-entered
+			<-entered
+			close(release)
+			for i := 0; i < 2; i++ {
+				var err error
+				Eventually(errs).Should(Receive(&err))
+				Expect(errors.Is(err, ErrCycle)).To(BeTrue())
+			}
+		})
+	})
+
+	Context("optional resolution", func() {
+		It("should return false when the instance isn't registered", func() {
+			var v int
+			found, err := container.ResolveNamedOptional(&v, "")
+			Expect(found).To(BeFalse())
+			Expect(err).To(BeNil())
+			_, found, err = ResolveOptionalAs[string](container, "missing")
+			Expect(found).To(BeFalse())
+			Expect(err).To(BeNil())
+		})
+		It("should resolve registered instances and values", func() {
+			container.MustRegisterInstance(1)
+			container.MustSetNamed("test", "name")
+			var v int
+			found, err := ResolveOptional(container, &v, "")
+			Expect(found).To(BeTrue())
+			Expect(err).To(BeNil())
+			Expect(v).To(Equal(1))
+			s, found, err := ResolveOptionalAs[string](container, "name")
+			Expect(found).To(BeTrue())
+			Expect(err).To(BeNil())
+			Expect(s).To(Equal("test"))
+		})
+		It("should resolve using any Factory", func() {
+			values := NewValues()
+			var v int
+			found, err := ResolveOptional(values, &v, "")
+			Expect(found).To(BeFalse())
+			Expect(err).To(BeNil())
+			values.MustSet(1)
+			found, err = ResolveOptional(struct{ Factory }{values}, &v, "")
+			Expect(found).To(BeTrue())
+			Expect(err).To(BeNil())
+			Expect(v).To(Equal(1))
+		})
+		Context("should return an error when", func() {
+			It("an error was returned when (*Registration).CreateInstance was called.", func() {
+				container.MustRegister(func(Factory) (interface{}, error) {
+					return nil, fmt.Errorf("Something went wrong")
+				}, (*int)(nil), PerRequest)
+				var v int
+				_, err := container.ResolveNamedOptional(&v, "")
+				Expect(err).ToNot(BeNil())
+			})
+			It("a dependency of the instance isn't registered", func() {
+				container.MustRegister(func(factory Factory) (interface{}, error) {
+					var s string
+					if err := Resolve(factory, &s); err != nil {
+						return nil, err
+					}
+					return len(s), nil
+				}, (*int)(nil), PerRequest)
+				var v int
+				_, err := ResolveOptional(container, &v, "")
+				Expect(errors.Is(err, ErrNotFound)).To(BeTrue())
+			})
+		})
+	})
+
+	Context("decorators", func() {
+		type N interface{ Name() string }
+		It("should apply the decorators in the order they were added", func() {
+			container.MustRegister(func(Factory) (interface{}, error) { return "a", nil }, (*string)(nil), PerContainer)
+			container.MustDecorate(func(factory Factory, v interface{}) (interface{}, error) { return v.(string) + "b", nil }, (*string)(nil))
+			MustDecorate(container, func(factory Factory, v string) (string, error) { return v + "c", nil })
+			var v string
+			container.MustResolve(&v)
+			Expect(v).To(Equal("abc"))
+			registrations := container.Registrations()
+			Expect(registrations).To(HaveLen(1))
+			Expect(registrations[0].Decorators).To(HaveLen(2))
+		})
+		It("should decorate registrations made after the decorator was added", func() {
+			MustDecorate(container, func(factory Factory, v N) (N, error) {
+				return &namedTestStruct{name: "decorated " + v.Name()}, nil
+			}, WithName("n"))
+			container.MustRegisterNamed(func(Factory) (interface{}, error) { return &namedTestStruct{name: "a"}, nil }, (*N)(nil), "n", PerRequest)
+			container.MustRegisterNamed(func(Factory) (interface{}, error) { return &namedTestStruct{name: "b"}, nil }, (*N)(nil), "n", PerRequest)
+			var n N
+			container.MustResolveNamed(&n, "n")
+			Expect(n.Name()).To(Equal("decorated b"))
+		})
+		It("should decorate multi registrations and pointer types", func() {
+			container.MustAdd(func(Factory) (interface{}, error) { return &namedTestStruct{name: "a"}, nil }, (**namedTestStruct)(nil), PerContainer)
+			var v []*namedTestStruct
+			container.MustResolveAll(&v)
+			MustDecorate(container, func(factory Factory, v *namedTestStruct) (*namedTestStruct, error) {
+				v.name += "!"
+				return v, nil
+			})
+			container.MustAdd(func(Factory) (interface{}, error) { return namedTestStruct{name: "b"}, nil }, (**namedTestStruct)(nil), PerContainer)
+			container.MustResolveAll(&v)
+			Expect(v).To(HaveLen(2))
+			// the singleton created before the decorator was added isn't decorated
+			Expect(v[0].name).To(Equal("a"))
+			Expect(v[1].name).To(Equal("b!"))
+		})
+		Context("should return an error when", func() {
+			It("decorator is nil", func() {
+				Expect(errors.Is(container.Decorate(nil, (*int)(nil)), ErrCreateInstanceNil)).To(BeTrue())
+			})
+			It("decorator returns an error", func() {
+				container.MustRegister(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerContainer)
+				MustDecorate(container, func(factory Factory, v int) (int, error) { return 0, fmt.Errorf("Something went wrong") })
+				var v int
+				Expect(errors.Is(container.Resolve(&v), ErrCreateInstance)).To(BeTrue())
+			})
+			It("decorator returns the wrong value type", func() {
+				container.MustRegister(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerContainer)
+				container.MustDecorate(func(factory Factory, v interface{}) (interface{}, error) { return "wrong", nil }, (*int)(nil))
+				var v int
+				Expect(errors.Is(container.Resolve(&v), ErrUnexpectedValueType)).To(BeTrue())
+			})
+		})
+	})
+
+	Context("named map resolution", func() {
+		It("should resolve every named registration and value", func() {
+			container.MustRegisterNamed(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), "one", PerContainer)
+			container.MustAddNamed(func(Factory) (interface{}, error) { return 2, nil }, (*int)(nil), "two", PerRequest)
+			container.MustRegisterNamedInstance(3, "three")
+			container.MustSetNamed(4, "four")
+			scopedContainer := container.Scope()
+			scopedContainer.MustSetNamed(5, "five")
+			scopedContainer.MustSetNamed(6, "four")
+			var v map[string]int
+			scopedContainer.MustResolveNamedMap(&v)
+			Expect(v).To(Equal(map[string]int{"one": 1, "two": 2, "three": 3, "four": 6, "five": 5}))
+			v = nil
+			container.MustResolveNamedMap(&v)
+			Expect(v).To(Equal(map[string]int{"one": 1, "two": 2, "three": 3, "four": 4}))
+		})
+		It("should resolve every named instance within a factory function", func() {
+			container.MustRegisterNamedInstance(&namedTestStruct{name: "a"}, "a")
+			container.MustRegisterNamedInstance(&namedTestStruct{name: "b"}, "b")
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				v, err := ResolveNamedMapAs[*namedTestStruct](factory)
+				if err != nil {
+					return nil, err
+				}
+				return v["a"].name + v["b"].name, nil
+			}, (*string)(nil), PerRequest)
+			var v string
+			container.MustResolve(&v)
+			Expect(v).To(Equal("ab"))
+		})
+		Context("should return an error when", func() {
+			It("value isn't a pointer to a map with a string key type", func() {
+				var v map[int]int
+				Expect(container.ResolveNamedMap(&v)).To(MatchError(ErrRequireMap))
+				var v1 []int
+				Expect(container.ResolveNamedMap(&v1)).To(MatchError(ErrRequireMap))
+			})
+			It("an instance can't be created", func() {
+				container.MustRegisterNamed(func(Factory) (interface{}, error) {
+					return nil, fmt.Errorf("Something went wrong")
+				}, (*int)(nil), "one", PerContainer)
+				var v map[string]int
+				Expect(container.ResolveNamedMap(&v)).To(MatchError(ErrCreateInstance))
+			})
+		})
+	})
+
+	Context("constructor registrations", func() {
+		type V struct{ name string }
+		type N interface{ Name() string }
+		It("should resolve the constructor parameters", func() {
+			container.MustRegisterInstance("test")
+			container.MustRegisterConstructor(func(name string, factory Factory) (*V, error) {
+				Expect(factory).ToNot(BeNil())
+				return &V{name: name}, nil
+			}, PerContainer)
+			var v *V
+			container.MustResolve(&v)
+			Expect(v.name).To(Equal("test"))
+		})
+		It("should register the constructor as an interface", func() {
+			container.MustRegisterConstructor(func() *namedTestStruct { return &namedTestStruct{name: "test"} }, PerRequest, As((*N)(nil)), WithName("n"))
+			var n N
+			container.MustResolveNamed(&n, "n")
+			Expect(n.Name()).To(Equal("test"))
+		})
+		It("should record the dependencies", func() {
+			container.MustRegisterConstructor(func(name string, v *V) int { return 1 }, PerContainer)
+			registrations := container.Registrations()
+			Expect(registrations).To(HaveLen(1))
+			Expect(registrations[0].Dependencies).To(HaveLen(2))
+			Expect(registrations[0].Dependencies[1].Name()).To(Equal("V"))
+		})
+		It("should validate the dependencies", func() {
+			container.MustRegisterConstructor(func(name string) int { return 1 }, PerContainer)
+			Expect(container.Validate()).To(MatchError(ErrUnresolvedDependency))
+			container.MustRegisterInstance("test")
+			Expect(container.Validate()).To(BeNil())
+		})
+		It("should validate the type provided by a provider dependency", func() {
+			container.MustRegisterConstructor(func(lazy Lazy[*V], newV func() (*V, error), name func() string) int { return 1 }, PerContainer)
+			Expect(container.Validate()).To(MatchError(ErrUnresolvedDependency))
+			container.MustRegisterConstructor(func() *V { return &V{} }, PerContainer)
+			Expect(container.Validate()).To(MatchError(ErrUnresolvedDependency))
+			container.MustRegisterInstance("test")
+			Expect(container.Validate()).To(BeNil())
+		})
+		Context("should return an error when", func() {
+			It("constructor isn't a function", func() {
+				Expect(container.RegisterConstructor(1, PerContainer)).To(MatchError(ErrInvalidConstructor))
+			})
+			It("constructor is nil", func() {
+				Expect(container.RegisterConstructor((func() int)(nil), PerContainer)).To(MatchError(ErrInvalidConstructor))
+			})
+			It("constructor signature isn't supported", func() {
+				Expect(container.RegisterConstructor(func() {}, PerContainer)).To(MatchError(ErrInvalidConstructor))
+				Expect(container.RegisterConstructor(func() (int, int) { return 1, 1 }, PerContainer)).To(MatchError(ErrInvalidConstructor))
+				Expect(container.RegisterConstructor(func(...int) int { return 1 }, PerContainer)).To(MatchError(ErrInvalidConstructor))
+			})
+			It("the lifetime is set using the WithLifetime option", func() {
+				err := container.RegisterConstructor(func() int { return 1 }, PerContainer, WithLifetime(PerScope))
+				Expect(err).To(MatchError(ErrInvalidConstructor))
+				Expect(err.Error()).To(ContainSubstring("WithLifetime"))
+				Expect(container.Registrations()).To(BeEmpty())
+			})
+			It("constructor return type doesn't implement the interface", func() {
+				Expect(container.RegisterConstructor(func() int { return 1 }, PerContainer, As((*N)(nil)))).ToNot(BeNil())
+			})
+			It("constructor returns an error", func() {
+				container.MustRegisterConstructor(func() (int, error) { return 0, fmt.Errorf("Something went wrong") }, PerContainer)
+				var v int
+				Expect(container.Resolve(&v)).To(MatchError(ErrCreateInstance))
+			})
+		})
+	})
+
+	Context("multi registrations", func() {
+		It("should resolve all instances in the order they were added", func() {
+			container.MustAdd(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerContainer)
+			container.MustAdd(func(Factory) (interface{}, error) { return 2, nil }, (*int)(nil), PerScope)
+			container.MustAdd(func(Factory) (interface{}, error) { return 3, nil }, (*int)(nil), PerRequest)
+			var v []int
+			container.MustResolveAll(&v)
+			Expect(v).To(Equal([]int{1, 2, 3}))
+			Expect(container.Registrations()).To(HaveLen(3))
+		})
+		It("should honor the lifetime of each registration", func() {
+			x := 1
+			container.MustAdd(func(Factory) (interface{}, error) { return x, nil }, (*int)(nil), PerContainer)
+			container.MustAdd(func(Factory) (interface{}, error) { return x, nil }, (*int)(nil), PerRequest)
+			var v []int
+			container.MustResolveAll(&v)
+			Expect(v).To(Equal([]int{1, 1}))
+			x = 2
+			container.MustResolveAll(&v)
+			Expect(v).To(Equal([]int{1, 2}))
+		})
+		It("should resolve pointer and interface elements", func() {
+			container.MustAddNamed(func(Factory) (interface{}, error) { return &namedTestStruct{name: "a"}, nil }, (**namedTestStruct)(nil), "n", PerContainer)
+			container.MustAddNamed(func(Factory) (interface{}, error) { return &namedTestStruct{name: "b"}, nil }, (**namedTestStruct)(nil), "n", PerContainer)
+			var v []*namedTestStruct
+			container.MustResolveAllNamed(&v, "n")
+			Expect(v).To(HaveLen(2))
+			Expect(v[0].name).To(Equal("a"))
+			Expect(v[1].name).To(Equal("b"))
+			type N interface{ Name() string }
+			container.MustAdd(func(Factory) (interface{}, error) { return &namedTestStruct{name: "c"}, nil }, (*N)(nil), PerRequest)
+			ns, err := ResolveAllAs[N](container)
+			Expect(err).To(BeNil())
+			Expect(ns).To(HaveLen(1))
+			Expect(ns[0].Name()).To(Equal("c"))
+		})
+		It("should resolve the last added instance by type", func() {
+			container.MustAdd(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerContainer)
+			container.MustAdd(func(Factory) (interface{}, error) { return 2, nil }, (*int)(nil), PerContainer)
+			var v int
+			container.MustResolve(&v)
+			Expect(v).To(Equal(2))
+		})
+		It("should resolve all instances within a factory function", func() {
+			container.MustAdd(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerContainer)
+			container.MustAdd(func(Factory) (interface{}, error) { return 2, nil }, (*int)(nil), PerContainer)
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				var v []int
+				if err := ResolveAll(factory, &v); err != nil {
+					return nil, err
+				}
+				return len(v), nil
+			}, (*int)(nil), PerRequest)
+			var v int
+			container.MustResolve(&v)
+			Expect(v).To(Equal(2))
+		})
+		It("should resolve an empty slice when nothing was added", func() {
+			var v []int
+			container.MustResolveAll(&v)
+			Expect(v).To(BeEmpty())
+		})
+		Context("should return an error when", func() {
+			It("value isn't a pointer to a slice", func() {
+				var v int
+				Expect(container.ResolveAll(&v)).To(MatchError(ErrRequireSlice))
+			})
+			It("factory doesn't implement MultiFactory", func() {
+				var v []int
+				Expect(ResolveAll(NewValues(), &v)).To(MatchError(ErrUnsupportedFactory))
+			})
+		})
+	})
+
+	Context("scoped registrations", func() {
+		It("should only be visible to the scope and its descendants", func() {
+			scope := container.Scope()
+			scope.MustRegister(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerRequest)
+			Expect(MustResolveAs[int](scope)).To(Equal(1))
+			Expect(MustResolveAs[int](scope.Scope())).To(Equal(1))
+			_, err := ResolveAs[int](container)
+			Expect(err).To(MatchError(ErrNotFound))
+			_, err = ResolveAs[int](container.Scope())
+			Expect(err).To(MatchError(ErrNotFound))
+		})
+		It("should override a registration of an ancestor", func() {
+			container.MustRegister(func(Factory) (interface{}, error) { return "root", nil }, (*string)(nil), PerContainer)
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				return len(MustResolveAs[string](factory)), nil
+			}, (*int)(nil), PerContainer)
+			scope := container.Scope()
+			scope.MustRegister(func(Factory) (interface{}, error) { return "scope", nil }, (*string)(nil), PerContainer)
+			Expect(MustResolveAs[string](scope.Scope())).To(Equal("scope"))
+			Expect(MustResolveAs[string](container)).To(Equal("root"))
+			// the dependencies of a root registration are resolved from the root container
+			Expect(MustResolveAs[int](scope)).To(Equal(4))
+		})
+		It("should cache Per Container instances on the scope the registration was made on", func() {
+			x := 0
+			scope := container.Scope()
+			scope.MustRegister(func(Factory) (interface{}, error) { x++; return x, nil }, (*int)(nil), PerContainer)
+			Expect(MustResolveAs[int](scope.Scope())).To(Equal(1))
+			Expect(MustResolveAs[int](scope.Scope())).To(Equal(1))
+			Expect(MustResolveAs[int](scope)).To(Equal(1))
+			other := container.Scope()
+			other.MustRegister(func(Factory) (interface{}, error) { x++; return x, nil }, (*int)(nil), PerContainer)
+			Expect(MustResolveAs[int](other)).To(Equal(2))
+		})
+		It("should not store an instance registered on a scope on the root container", func() {
+			scope := container.Scope()
+			scope.MustRegisterInstance(1)
+			Expect(MustResolveAs[int](scope.Scope())).To(Equal(1))
+			Expect(container.instances.get(reflect.TypeOf(0), "")).To(BeNil())
+			_, err := ResolveAs[int](container)
+			Expect(err).To(MatchError(ErrNotFound))
+		})
+		It("should resolve the multi registrations of the ancestors first", func() {
+			container.MustAdd(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerRequest)
+			scope := container.Scope()
+			scope.MustAdd(func(Factory) (interface{}, error) { return 2, nil }, (*int)(nil), PerRequest)
+			var all []int
+			scope.MustResolveAll(&all)
+			Expect(all).To(Equal([]int{1, 2}))
+			container.MustResolveAll(&all)
+			Expect(all).To(Equal([]int{1}))
+		})
+		It("should be discarded when the scope is closed", func() {
+			container.MustRegister(func(Factory) (interface{}, error) { return "root", nil }, (*string)(nil), PerContainer)
+			scope := container.Scope()
+			scope.MustRegister(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerRequest)
+			scope.MustRegister(func(Factory) (interface{}, error) { return "scope", nil }, (*string)(nil), PerContainer)
+			Expect(scope.Registrations()).To(HaveLen(2))
+			Expect(scope.Close()).To(Succeed())
+			registrations := scope.Registrations()
+			Expect(registrations).To(HaveLen(1))
+			Expect(registrations[0].Type).To(Equal(reflect.TypeOf("")))
+			Expect(registrations[0].scope).To(BeNil())
+		})
+	})
+
 	Context("should return an error when", func() {
 		It("instance lifetime isn't supported", func() {
 			err := container.RegisterNamed(func(factory Factory) (interface{}, error) {
@@ -344,7 +1002,11 @@ var _ = Describe("Container", func() {
 				}
 				return v, nil
 			}, (*int)(nil), "", Lifetime(6))
-			Expect(err).ToNot(BeNil())
+			Expect(err).To(MatchError(ErrUnsupportedLifetime))
 		})
 	})
 })
+
+type namedTestStruct struct{ name string }
+
+func (v *namedTestStruct) Name() string { return v.name }
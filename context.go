@@ -0,0 +1,113 @@
+package ioc
+
+import "context"
+
+// containerKey is the context key of a container.
+type containerKey struct{}
+
+// WithContainer returns a copy of the context carrying the container.
+//
+// The container is retrieved using FromContext or resolved from using FactoryFromContext.
+func WithContainer(ctx context.Context, c *Container) context.Context {
+	return context.WithValue(ctx, containerKey{}, c)
+}
+
+// FromContext returns the container carried by the context.
+//
+// Returns false when the context doesn't carry a container. (see WithContainer)
+func FromContext(ctx context.Context) (*Container, bool) {
+	c, ok := ctx.Value(containerKey{}).(*Container)
+	return c, ok && c != nil
+}
+
+// FactoryFromContext returns a Factory resolving instances from the container carried by the context,
+// using the context. (see (*Container).ResolveNamedContext)
+//
+// The container is retrieved from the context on every resolve call.
+// Resolve calls return an error with the ErrNoContainer error code when the context doesn't carry a container.
+func FactoryFromContext(ctx context.Context) Factory {
+	return contextFactory{ctx}
+}
+
+// WithResolutionFromContext calls fn with a Factory resolving instances within one resolution
+// from the container carried by the context, using the context. (see (*Container).WithResolution)
+//
+// Returns an error with the ErrNoContainer error code when the context doesn't carry a container,
+// or the error returned by fn.
+func WithResolutionFromContext(ctx context.Context, fn func(factory Factory) error) error {
+	c, err := contextFactory{ctx}.container()
+	if err != nil {
+		return err
+	}
+	return c.WithResolution(ctx, fn)
+}
+
+// contextFactory resolves instances from the container carried by a context.
+type contextFactory struct {
+	ctx context.Context
+}
+
+// Get the container carried by the context.
+func (factory contextFactory) container() (*Container, error) {
+	c, ok := FromContext(factory.ctx)
+	if !ok {
+		return nil, errNoContainer()
+	}
+	return c, nil
+}
+
+// Context returns the context.
+func (factory contextFactory) Context() context.Context {
+	return factory.ctx
+}
+
+// Resolve a named instance by type.
+//
+// ResolveNamed calls ResolveNamedContext(ctx, v, name) on the container carried by the context.
+func (factory contextFactory) ResolveNamed(v interface{}, name string) error {
+	return factory.ResolveNamedContext(factory.ctx, v, name)
+}
+
+// Resolve a named instance by type using a context.
+//
+// ResolveNamedContext calls ResolveNamedContext(ctx, v, name) on the container carried by the factory context.
+func (factory contextFactory) ResolveNamedContext(ctx context.Context, v interface{}, name string) error {
+	c, err := factory.container()
+	if err != nil {
+		return err
+	}
+	return c.ResolveNamedContext(ctx, v, name)
+}
+
+// Resolve an optional named instance by type.
+//
+// ResolveNamedOptional resolves the instance from the container carried by the context, using the context.
+func (factory contextFactory) ResolveNamedOptional(v interface{}, name string) (bool, error) {
+	c, err := factory.container()
+	if err != nil {
+		return false, err
+	}
+	return c.resolveNamedOptionalContext(factory.ctx, v, name)
+}
+
+// Resolve every instance added for a type and name.
+//
+// ResolveAllNamed resolves the instances from the container carried by the context, using the context.
+func (factory contextFactory) ResolveAllNamed(v interface{}, name string) error {
+	c, err := factory.container()
+	if err != nil {
+		return err
+	}
+	return c.resolveAllNamedContext(factory.ctx, v, name)
+}
+
+// Resolve every named instance of a type.
+//
+// ResolveNamedMap resolves the instances from the container carried by the context, using the context.
+func (factory contextFactory) ResolveNamedMap(v interface{}) error {
+	c, err := factory.container()
+	if err != nil {
+		return err
+	}
+	return c.resolveNamedMapContext(factory.ctx, v)
+}
@@ -0,0 +1,94 @@
+package ioc
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// to test
+// WithContainer/FromContext
+// FactoryFromContext
+// WithResolutionFromContext
+
+var _ = Describe("Context", func() {
+	var container *Container
+	BeforeEach(func() { container = NewContainer() })
+
+	It("should carry the container", func() {
+		_, ok := FromContext(context.Background())
+		Expect(ok).To(BeFalse())
+		ctx := WithContainer(context.Background(), container)
+		c, ok := FromContext(ctx)
+		Expect(ok).To(BeTrue())
+		Expect(c).To(Equal(container))
+	})
+	It("should resolve from the container carried by the context", func() {
+		type contextKey struct{}
+		container.MustRegisterContext(func(ctx context.Context, factory Factory) (interface{}, error) {
+			return ctx.Value(contextKey{}).(string), nil
+		}, (*string)(nil), PerScope)
+		container.MustAdd(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerContainer)
+		scopedContainer := container.Scope()
+		ctx := context.WithValue(WithContainer(context.Background(), scopedContainer), contextKey{}, "value")
+		factory := FactoryFromContext(ctx)
+		Expect(MustResolveAs[string](factory)).To(Equal("value"))
+		Expect(ResolveAllAs[int](factory)).To(Equal([]int{1}))
+		_, found, err := ResolveOptionalAs[float64](factory, "")
+		Expect(found).To(BeFalse())
+		Expect(err).To(BeNil())
+		// the instance is resolved from the scoped container
+		scopedContainer.MustSet(1.5)
+		Expect(MustResolveAs[float64](factory)).To(Equal(1.5))
+	})
+	It("should pass the context to every resolve call", func() {
+		type contextKey struct{}
+		fromContext := func(ctx context.Context, factory Factory) (interface{}, error) {
+			return ctx.Value(contextKey{}).(string), nil
+		}
+		container.MustRegisterContext(fromContext, (*string)(nil), PerRequest)
+		container.MustAdd(func(factory Factory) (interface{}, error) {
+			return fromContext(factory.(ContextFactory).Context(), factory)
+		}, (*string)(nil), PerRequest)
+		container.MustRegisterNamedContext(fromContext, (*string)(nil), "named", PerRequest)
+		ctx, cancel := context.WithCancel(context.WithValue(WithContainer(context.Background(), container), contextKey{}, "value"))
+		factory := FactoryFromContext(ctx)
+		s, found, err := ResolveOptionalAs[string](factory, "")
+		Expect(found).To(BeTrue())
+		Expect(err).To(BeNil())
+		Expect(s).To(Equal("value"))
+		Expect(ResolveAllAs[string](factory)).To(Equal([]string{"value"}))
+		Expect(ResolveNamedMapAs[string](factory)).To(HaveKeyWithValue("named", "value"))
+		cancel()
+		_, _, err = ResolveOptionalAs[string](factory, "")
+		Expect(err).To(MatchError(ErrContextDone))
+		_, err = ResolveAllAs[string](factory)
+		Expect(err).To(MatchError(ErrContextDone))
+	})
+	It("should resolve within one resolution from the container carried by the context", func() {
+		x := 0
+		container.MustRegister(func(Factory) (interface{}, error) { x++; return x, nil }, (*int)(nil), PerResolve)
+		ctx := WithContainer(context.Background(), container)
+		resolve := func() (a, b int) {
+			Expect(WithResolutionFromContext(ctx, func(factory Factory) error {
+				a = MustResolveAs[int](factory)
+				b = MustResolveAs[int](factory)
+				return nil
+			})).To(Succeed())
+			return a, b
+		}
+		a, b := resolve()
+		Expect(a).To(Equal(1))
+		Expect(b).To(Equal(1))
+		resolve()
+		Expect(x).To(Equal(2))
+	})
+	Context("should return an error when", func() {
+		It("the context doesn't carry a container", func() {
+			_, err := ResolveAs[int](FactoryFromContext(context.Background()))
+			Expect(err).To(MatchError(ErrNoContainer))
+			Expect(WithResolutionFromContext(context.Background(), func(Factory) error { return nil })).To(MatchError(ErrNoContainer))
+		})
+	})
+})
@@ -1,75 +1,300 @@
 package ioc
 
 import (
+	"context"
 	"reflect"
 	"sync"
 )
 
-// RecursionLimit specifies the maximum count resolve can be called for a type and name
-// before an error is raised, to avoid infinite recursion.
+// dependencyResolverGraph tracks whether a resolution is in progress
+// and caches the Per Resolve instances of the resolution by registration id.
 //
-// A limit of 1 is sufficient for detecting infinite recursion
-// for the Per Container and Per Scope lifetimes.
-//
-// An (infinite -1) limit is required for detecting infinite recursion
-// for the Per Request lifetime.
-//
-// Due to the infinite limit required to accurately detect infinite recursion
-// for the Per Request lifetime, you should set the
-// RecursionLimit to a reasonable setting.
-var RecursionLimit int = 30
-
-// dependencyResolverGraph tracks the calls to resolve for a type and name
-// to detect infinite recursion.
+// A resolution starts with a call to resolve on a Container and is done when the call returns.
 type dependencyResolverGraph struct {
-	m      *sync.Mutex
-	lookup map[reflect.Type]map[string]int
+	m         *sync.Mutex
+	done      bool
+	instances map[uint64]*reflect.Value
+	// waiting is the in-progress construction of another resolution the resolution waits on. (guarded by waits)
+	waiting *construction
 }
 
+// waits guards the constructions the resolutions wait on, to detect resolutions waiting on each other.
+var waits sync.Mutex
+
 // newDependencyResolverGraph creates a new dependencyResolverGraph.
 func newDependencyResolverGraph() *dependencyResolverGraph {
-	return &dependencyResolverGraph{new(sync.Mutex), make(map[reflect.Type]map[string]int)}
+	return &dependencyResolverGraph{m: new(sync.Mutex), instances: make(map[uint64]*reflect.Value)}
 }
 
-// Tracks the number of times resolve is called for a type and name.
-//
-// Returns true while the count is less than the RecursionLimit.
-func (g *dependencyResolverGraph) track(typ reflect.Type, name string) bool {
+// Get the Per Resolve instance of a registration.
+func (g *dependencyResolverGraph) get(registration *Registration) *reflect.Value {
 	g.m.Lock()
-	if named, ok := g.lookup[typ]; ok {
-		var count int
-		if count, ok = named[name]; ok {
-			count += 1
-			if count >= RecursionLimit {
-				return false
-			}
-		} else {
-			count = 1
-		}
-		named[name] = count
-	} else {
-		g.lookup[typ] = map[string]int{name: 1}
-	}
+	instance := g.instances[registration.id]
 	g.m.Unlock()
-	return true
+	return instance
+}
+
+// Set the Per Resolve instance of a registration.
+func (g *dependencyResolverGraph) set(registration *Registration, instance *reflect.Value) {
+	g.m.Lock()
+	g.instances[registration.id] = instance
+	g.m.Unlock()
+}
+
+// finish marks the resolution represented by the graph as done.
+func (g *dependencyResolverGraph) finish() {
+	g.m.Lock()
+	g.done = true
+	g.m.Unlock()
+}
+
+// finished returns true when the resolution represented by the graph is done.
+func (g *dependencyResolverGraph) finished() bool {
+	g.m.Lock()
+	done := g.done
+	g.m.Unlock()
+	return done
 }
 
-// dependencyResolver tracks the resolve calls for a type and name, and proxies resolve calls to a Container.
+// construction is an in-progress construction of a singleton instance.
+type construction struct {
+	done     chan struct{}
+	instance *reflect.Value
+	err      error
+	// waiters is the number of resolve calls waiting on the construction.
+	waiters int
+	// registration is the registration of the instance being constructed.
+	registration *Registration
+	// owner is the resolution constructing the instance, or nil when unknown.
+	owner *dependencyResolverGraph
+}
+
+// constructions tracks the in-progress constructions of the singleton instances of a container
+// to ensure the factory function of a registration is called at most once per container.
+type constructions struct {
+	m        *sync.Mutex
+	inflight map[uint64]*construction
+}
+
+// newConstructions creates a new constructions.
+func newConstructions() *constructions {
+	return &constructions{m: new(sync.Mutex), inflight: make(map[uint64]*construction)}
+}
+
+// begin a construction for a registration by a resolution. (owner is nil when unknown)
+//
+// Returns the in-progress construction and false when another resolve call is constructing the instance.
+func (c *constructions) begin(registration *Registration, owner *dependencyResolverGraph) (*construction, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if call, ok := c.inflight[registration.id]; ok {
+		call.waiters++
+		return call, false
+	}
+	call := &construction{done: make(chan struct{}), registration: registration, owner: owner}
+	c.inflight[registration.id] = call
+	return call, true
+}
+
+// Get the number of resolve calls waiting on the in-progress construction of a registration.
+func (c *constructions) waiting(registration *Registration) int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if call, ok := c.inflight[registration.id]; ok {
+		return call.waiters
+	}
+	return 0
+}
+
+// end a construction and notify the waiting resolve calls.
+func (c *constructions) end(registration *Registration, call *construction, instance *reflect.Value, err error) {
+	call.instance, call.err = instance, err
+	c.m.Lock()
+	delete(c.inflight, registration.id)
+	c.m.Unlock()
+	close(call.done)
+}
+
+// dependencyResolver tracks the path of registrations being created, and proxies resolve calls to a Container.
 //
-// dependencyResolver uses a dependencyResolverGraph to track the calls to resolve for a type and name
-// to detect infinite recursion.
+// dependencyResolver detects infinite recursion when a registration is resolved while it's being created,
+// i.e. the registration is already on the resolution path.
 //
 // Resolve calls within a factory function are passed either the current (scoped) dependency resolver or
-// a new root container level dependency resolver inheriting
-// the dependencyResolverGraph from the parent dependencyResolver.
+// a new root container level dependency resolver, inheriting the dependencyResolverGraph
+// and the resolution path from the parent dependencyResolver.
 type dependencyResolver struct {
-	c *Container
-	g *dependencyResolverGraph
+	ctx  context.Context
+	c    *Container
+	g    *dependencyResolverGraph
+	path []ResolveStep
+}
+
+// resolveContext is the context passed to the lifetime managers, carrying the dependency resolver of the request.
+type resolveContext struct {
+	context.Context
+	resolver *dependencyResolver
+}
+
+// resolverKey is the context key of the dependency resolver carried by a resolveContext.
+type resolverKey struct{}
+
+func (ctx *resolveContext) Value(key interface{}) interface{} {
+	if key == (resolverKey{}) {
+		return ctx.resolver
+	}
+	return ctx.Context.Value(key)
+}
+
+// resolverFromContext returns the dependency resolver carried by the context, or nil.
+func resolverFromContext(ctx context.Context) *dependencyResolver {
+	resolver, _ := ctx.Value(resolverKey{}).(*dependencyResolver)
+	return resolver
 }
 
 // newDependencyResolver creates a new newDependencyResolver.
-func newDependencyResolver(c *Container, g *dependencyResolverGraph) *dependencyResolver {
-	return &dependencyResolver{c, g}
+func newDependencyResolver(ctx context.Context, c *Container, g *dependencyResolverGraph) *dependencyResolver {
+	return &dependencyResolver{ctx: ctx, c: c, g: g}
+}
+
+// checkPath returns an error when the registration is already on the resolution path.
+func (resolver *dependencyResolver) checkPath(registration *Registration, step ResolveStep) error {
+	for i, s := range resolver.path {
+		if s.id == registration.id {
+			cycle := formatPath(append(resolver.path[i:len(resolver.path):len(resolver.path)], step))
+			return resolver.withPath(errResolveInfiniteRecursion(registration.Type, registration.Name, cycle), &step)
+		}
+	}
+	return nil
+}
+
+// waitFor marks the resolution as waiting on the in-progress construction of an instance.
+//
+// Returns an error when the construction waits on a construction of the resolution, directly or through the constructions
+// other resolutions wait on, i.e. the resolutions would wait on each other forever.
+func (resolver *dependencyResolver) waitFor(call *construction) error {
+	waits.Lock()
+	defer waits.Unlock()
+	var chain []*construction
+	for x := call; x != nil && x.owner != nil; x = x.owner.waiting {
+		select {
+		case <-x.done:
+			// the owner of a finished construction doesn't wait
+			resolver.g.waiting = call
+			return nil
+		default:
+		}
+		chain = append(chain, x)
+		if x.owner == resolver.g {
+			return resolver.waitCycle(chain)
+		}
+	}
+	resolver.g.waiting = call
+	return nil
+}
+
+// stopWaiting marks the resolution as no longer waiting on an in-progress construction.
+func (resolver *dependencyResolver) stopWaiting() {
+	waits.Lock()
+	resolver.g.waiting = nil
+	waits.Unlock()
+}
+
+// waitCycle creates the error for a chain of constructions waiting on a construction of the resolution.
+func (resolver *dependencyResolver) waitCycle(chain []*construction) error {
+	owned := chain[len(chain)-1].registration
+	steps := []ResolveStep{resolver.step(owned.Type, owned.Name, owned)}
+	for i, s := range resolver.path {
+		if s.id == owned.id {
+			steps = append([]ResolveStep(nil), resolver.path[i:]...)
+			break
+		}
+	}
+	for _, x := range chain {
+		steps = append(steps, resolver.step(x.registration.Type, x.registration.Name, x.registration))
+	}
+	registration := chain[0].registration
+	return errResolveInfiniteRecursion(registration.Type, registration.Name, formatPath(steps))
+}
+
+// creator returns the function creating an instance of a registration passed to the lifetime manager.
+func (resolver *dependencyResolver) creator(registration *Registration) func(owner *Container) (*reflect.Value, error) {
+	return func(owner *Container) (*reflect.Value, error) {
+		// dependencies inside the factory function (*Registration).CreateInstance
+		// are resolved from the owner container, e.g. the root container for the Per Container lifetime.
+		resolver1 := resolver.enter(owner, registration)
+		instance, err := registration.CreateInstance(resolver1)
+		if err != nil {
+			return nil, resolver1.withPath(err, nil)
+		}
+		return instance, nil
+	}
+}
+
+// detach creates a dependency resolver for a new resolution that isn't cancelled with the context of the request,
+// e.g. to re-create an instance in the background. The resolution is finished by calling resolver.g.finish().
+func (resolver *dependencyResolver) detach() *dependencyResolver {
+	detached := newDependencyResolver(context.WithoutCancel(resolver.ctx), resolver.c, newDependencyResolverGraph())
+	detached.path = resolver.path
+	return detached
+}
+
+// enter creates a dependency resolver for creating an instance of a registration owned by a container,
+// with the registration added to the resolution path.
+func (resolver *dependencyResolver) enter(owner *Container, registration *Registration) *dependencyResolver {
+	resolver1 := &dependencyResolver{ctx: resolver.ctx, c: owner, g: resolver.g}
+	path := make([]ResolveStep, len(resolver.path), len(resolver.path)+1)
+	copy(path, resolver.path)
+	resolver1.path = append(path, resolver1.step(registration.Type, registration.Name, registration))
+	return resolver1
+}
+
+// step creates a resolution path step for a named instance by type resolved by the container.
+//
+// registration is nil when the instance isn't created from a registration.
+func (resolver *dependencyResolver) step(typ reflect.Type, name string, registration *Registration) ResolveStep {
+	step := ResolveStep{Type: typ, Name: name, Scope: resolver.c.depth}
+	if registration != nil {
+		step.Lifetime = registration.Lifetime
+		step.Registered = true
+		step.id = registration.id
+	}
+	return step
+}
+
+// withPath sets the resolution path on a resolve error, followed by the step when not nil.
+//
+// The path of an error is only set once, by the dependency resolver closest to the cause of the error.
+// An error wrapping the resolve error of a dependency inherits the full resolution path from the dependency error.
+func (resolver *dependencyResolver) withPath(err error, step *ResolveStep) error {
+	e, ok := err.(*Error)
+	if !ok || e.Path != nil {
+		return err
+	}
+	if inner, ok := e.Inner.(*Error); ok && resolver.hasPrefix(inner.Path) {
+		e.Path = inner.Path
+		return err
+	}
+	path := make([]ResolveStep, len(resolver.path), len(resolver.path)+1)
+	copy(path, resolver.path)
+	if step != nil {
+		path = append(path, *step)
+	}
+	e.Path = path
+	return err
+}
+
+// hasPrefix returns true when the path continues the resolution path of the dependency resolver.
+func (resolver *dependencyResolver) hasPrefix(path []ResolveStep) bool {
+	if len(path) <= len(resolver.path) {
+		return false
+	}
+	for i, step := range resolver.path {
+		if path[i].id != step.id || path[i].Type != step.Type || path[i].Name != step.Name {
+			return false
+		}
+	}
+	return true
 }
 
 var typeContainer = reflect.TypeOf((*Container)(nil)).Elem()
@@ -86,85 +311,225 @@ var typeFactory = reflect.TypeOf((*Factory)(nil)).Elem()
 //	- The value isn't a pointer.
 //	- The value is a nil pointer e.g. (*string)(nil) (use a pointer to a (nil) pointer instead)
 //	- The dependency can't be resolved (not registered).
-//	- The instance lifetime isn't supported. Currently only PerContainer, PerScope and PerRequest lifetimes are supported.
+//	- The instance lifetime isn't supported. (see RegisterLifetime)
 //	- An error was returned when (*Registration).CreateInstance was called.
-//	- Infinite recursion is detected on a repetitive call to resolve an instance by type and name.
+//	- Infinite recursion is detected when an instance is resolved while it's being created. (a dependency cycle)
 func (resolver *dependencyResolver) ResolveNamed(v interface{}, name string) error {
 	instanceSetter, err := GetNamedSetter(v, name)
 	if err != nil {
 		return err
 	}
-	typ := instanceSetter.Type()
+	instance, err := resolver.resolve(instanceSetter.Type(), name)
+	if err != nil {
+		return err
+	}
+	instanceSetter.Set(*instance)
+	return nil
+}
+
+// Resolve a named instance by type using a context.
+//
+// The context is passed to the factory functions of the instances created for the request (see ContextFactory)
+// and aborts waiting on the in-progress construction of a singleton instance when the context is done.
+//
+// Returns an error when:
+//	- An instance can't be resolved. (see ResolveNamed)
+//	- The context is done.
+func (resolver *dependencyResolver) ResolveNamedContext(ctx context.Context, v interface{}, name string) error {
+	resolver1 := *resolver
+	resolver1.ctx = ctx
+	return resolver1.ResolveNamed(v, name)
+}
+
+// Context returns the context of the request.
+func (resolver *dependencyResolver) Context() context.Context {
+	return resolver.ctx
+}
+
+// Resolve an optional named instance by type.
+//
+// Returns false and no error when the dependency isn't registered, can't be found on the container Values and isn't a provider type.
+// found is true when an error is returned while creating a registered instance.
+//
+// Returns an error when:
+//	- The value type is nil.
+//	- The value isn't a pointer.
+//	- The value is a nil pointer e.g. (*string)(nil) (use a pointer to a (nil) pointer instead)
+//	- The instance lifetime isn't supported. (see RegisterLifetime)
+//	- An error was returned when (*Registration).CreateInstance was called.
+//	- Infinite recursion is detected when an instance is resolved while it's being created. (a dependency cycle)
+func (resolver *dependencyResolver) ResolveNamedOptional(v interface{}, name string) (bool, error) {
+	instanceSetter, err := GetNamedSetter(v, name)
+	if err != nil {
+		return false, err
+	}
+	instance, found, err := resolver.lookup(instanceSetter.Type(), name)
+	if !found || err != nil {
+		return found, err
+	}
+	instanceSetter.Set(*instance)
+	return true, nil
+}
+
+// resolve a named instance by type.
+func (resolver *dependencyResolver) resolve(typ reflect.Type, name string) (*reflect.Value, error) {
+	instance, found, err := resolver.lookup(typ, name)
+	if !found {
+		step := resolver.step(typ, name, nil)
+		return nil, resolver.withPath(errUnresolvedDependency(typ, name, resolver.suggest(typ, name)), &step)
+	}
+	return instance, err
+}
+
+// suggest the near matches for a named instance by type from the registry and the container values.
+func (resolver *dependencyResolver) suggest(typ reflect.Type, name string) []Suggestion {
+	candidates := make(typeNames)
+	resolver.c.r.typeNames(candidates)
+	resolver.c.Values.localTypeNames(candidates)
+	return suggest(typ, name, candidates)
+}
+
+// lookup a named instance by type.
+//
+// Returns false when the instance isn't registered, can't be found on the container Values and isn't a provider type.
+// No error is created in that case, because optional lookups are expected to miss.
+func (resolver *dependencyResolver) lookup(typ reflect.Type, name string) (*reflect.Value, bool, error) {
+	if resolver.c.isClosed() {
+		step := resolver.step(typ, name, nil)
+		return nil, true, resolver.withPath(errContainerClosed(typ, name), &step)
+	}
 	if name == "" {
 		switch typ {
 		case typeContainer:
 			instance := reflect.ValueOf(resolver.c).Elem()
-			instanceSetter.Set(instance)
-			return nil
+			return &instance, true, nil
 		case typeFactory:
 			var factory Factory = resolver
-			instance := reflect.ValueOf(factory)
-			instanceSetter.Set(instance)
-			return nil
+			instance := reflect.ValueOf(&factory).Elem()
+			return &instance, true, nil
 		}
 	}
 	// get the registration
 	registration := resolver.c.r.get(typ, name)
-	var instance *reflect.Value
 	if registration == nil {
 		// try to resolve using the scoped container values
-		if instance = resolver.c.get(typ, name); instance != nil {
-			instanceSetter.Set(*instance)
-			return nil
+		instance := resolver.c.get(typ, name)
+		if instance != nil {
+			return instance, true, nil
 		}
-		return errUnresolvedDependency(typ, name)
-	}
-	switch registration.Lifetime {
-	case PerContainer:
-		// create a dependency resolver for the root container
-		resolver1 := resolver
-		if resolver.c.root != nil {
-			resolver1 = newDependencyResolver(resolver.c.root, resolver.g)
+		// try to create a provider for the type
+		if instance = resolver.provide(typ, name); instance != nil {
+			return instance, true, nil
 		}
-		// the root dependency resolver should be used to resolve
-		// dependencies inside the factory function (*Registration).CreateInstance.
-		// further dependency resolution will occur at the root container scope
-		// i.e. no instances from the scoped container are available
-		instance, err = resolver1.resolveSingletonLifetime(registration)
-	case PerScope:
-		instance, err = resolver.resolveSingletonLifetime(registration)
-	case PerRequest:
-		instance, err = resolver.resolvePerRequestLifetime(registration)
-	default:
-		return errUnsupportedLifetime(registration.Type, registration.Name, registration.Lifetime)
+		return nil, false, nil
 	}
+	instance, err := resolver.resolveRegistration(registration)
+	return instance, true, err
+}
+
+// Resolve every instance added for a type and name.
+//
+// v must be a pointer to a slice. The slice element type is used to get the multi registrations.
+//
+// Returns an error when:
+//	- The value type is nil.
+//	- The value isn't a pointer to a slice.
+//	- An instance can't be resolved. (see ResolveNamed)
+func (resolver *dependencyResolver) ResolveAllNamed(v interface{}, name string) error {
+	instanceSetter, err := GetNamedSetter(v, name)
 	if err != nil {
 		return err
 	}
-	instanceSetter.Set(*instance)
+	sliceType := instanceSetter.Type()
+	if sliceType.Kind() != reflect.Slice {
+		return errRequireSlice(sliceType, name)
+	}
+	elemTyp := sliceType.Elem()
+	registrations := resolver.c.r.getMulti(elemType(elemTyp), name)
+	instances := reflect.MakeSlice(sliceType, 0, len(registrations))
+	for _, registration := range registrations {
+		instance, err := resolver.resolveRegistration(registration)
+		if err != nil {
+			return err
+		}
+		elem, err := newElem(elemTyp, instance, name)
+		if err != nil {
+			return err
+		}
+		instances = reflect.Append(instances, elem)
+	}
+	instanceSetter.Set(instances)
 	return nil
 }
 
-// resolve a singleton instance for the Per Container and Per Scope lifetimes.
-func (resolver *dependencyResolver) resolveSingletonLifetime(registration *Registration) (*reflect.Value, error) {
-	if instance := resolver.c.instances.get(registration.Type, registration.Name); instance != nil {
-		return instance, nil
+// Resolve every named instance of a type.
+//
+// v must be a pointer to a map with a string key type. The map element type is used to get the
+// names of the registrations and of the instances on the scoped container values.
+// Every name, including "", is resolved using the same rules as ResolveNamed.
+//
+// Returns an error when:
+//	- The value type is nil.
+//	- The value isn't a pointer to a map with a string key type.
+//	- An instance can't be resolved. (see ResolveNamed)
+func (resolver *dependencyResolver) ResolveNamedMap(v interface{}) error {
+	instanceSetter, err := GetNamedSetter(v, "")
+	if err != nil {
+		return err
 	}
-	if !resolver.g.track(registration.Type, registration.Name) {
-		return nil, errResolveInfiniteRecursion(registration.Type, registration.Name)
+	mapType := instanceSetter.Type()
+	if mapType.Kind() != reflect.Map || mapType.Key().Kind() != reflect.String {
+		return errRequireMap(mapType)
 	}
-	instance, err := registration.CreateInstance(resolver)
-	if err != nil {
-		return nil, err
+	elemTyp := mapType.Elem()
+	typ := elemType(elemTyp)
+	names := appendNames(resolver.c.r.names(typ), resolver.c.Values.localNames(typ)...)
+	instances := reflect.MakeMapWithSize(mapType, len(names))
+	for _, name := range names {
+		instance, err := resolver.resolve(typ, name)
+		if err != nil {
+			return err
+		}
+		elem, err := newElem(elemTyp, instance, name)
+		if err != nil {
+			return err
+		}
+		instances.SetMapIndex(reflect.ValueOf(name).Convert(mapType.Key()), elem)
 	}
-	resolver.c.instances.set(registration.Type, registration.Name, instance)
-	return instance, nil
+	instanceSetter.Set(instances)
+	return nil
 }
 
-// resolve an instance for the Per Request lifetime.
-func (resolver *dependencyResolver) resolvePerRequestLifetime(registration *Registration) (*reflect.Value, error) {
-	if !resolver.g.track(registration.Type, registration.Name) {
-		return nil, errResolveInfiniteRecursion(registration.Type, registration.Name)
+// resolve an instance for a registration using the LifetimeManager of the registration lifetime.
+func (resolver *dependencyResolver) resolveRegistration(registration *Registration) (*reflect.Value, error) {
+	step := resolver.step(registration.Type, registration.Name, registration)
+	// the cached instances of a closed container are disposed
+	if resolver.c.isClosed() {
+		return nil, resolver.withPath(errContainerClosed(registration.Type, registration.Name), &step)
+	}
+	if err := resolver.ctx.Err(); err != nil {
+		return nil, resolver.withPath(errContextDone(registration.Type, registration.Name, err), &step)
+	}
+	manager := getLifetimeManager(registration.Lifetime)
+	if manager == nil {
+		return nil, resolver.withPath(errUnsupportedLifetime(registration.Type, registration.Name, registration.Lifetime), &step)
+	}
+	// detect infinite recursion before the lifetime manager waits on an in-progress construction
+	if err := resolver.checkPath(registration, step); err != nil {
+		return nil, err
 	}
-	return registration.CreateInstance(resolver)
+	create := resolver.creator(registration)
+	var (
+		instance *reflect.Value
+		err      error
+	)
+	if graphManager, ok := manager.(graphLifetimeManager); ok {
+		instance, err = graphManager.resolveGraph(resolver.ctx, resolver.g, resolver.c, registration, create)
+	} else {
+		instance, err = manager.Resolve(&resolveContext{Context: resolver.ctx, resolver: resolver}, resolver.c, registration, create)
+	}
+	if err != nil {
+		return nil, resolver.withPath(err, &step)
+	}
+	return instance, nil
 }
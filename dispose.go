@@ -0,0 +1,272 @@
+package ioc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Disposer is implemented by instances that release resources using a context.
+//
+// Instances implementing Disposer or io.Closer are disposed when the container that owns them is closed.
+// Dispose takes precedence over Close when an instance implements both.
+type Disposer interface {
+	Dispose(ctx context.Context) error
+}
+
+// disposables tracks the instances owned by a container in the order they were created,
+// the open child scopes and whether the container is closed.
+//
+// Child scopes are stored by their disposables, which are shared by copies of a scoped container.
+type disposables struct {
+	m             *sync.Mutex
+	closed        bool
+	instances     []interface{}
+	scopes        map[*disposables]*Container
+	ownPerRequest bool
+}
+
+// newDisposables creates a new disposables.
+func newDisposables() *disposables {
+	return &disposables{m: new(sync.Mutex), scopes: make(map[*disposables]*Container)}
+}
+
+// addScope tracks an open child scope. Adding a tracked scope is a no-op.
+//
+// Returns false when the container is closed. The scope isn't tracked in that case.
+func (d *disposables) addScope(scope *Container) bool {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if d.closed {
+		return false
+	}
+	// a closed scope was removed by CloseContext
+	if !scope.d.isClosed() {
+		d.scopes[scope.d] = scope
+	}
+	return true
+}
+
+// removeScope stops tracking a child scope.
+func (d *disposables) removeScope(scope *Container) {
+	d.m.Lock()
+	delete(d.scopes, scope.d)
+	d.m.Unlock()
+}
+
+// Get the open child scopes.
+func (d *disposables) getScopes() []*Container {
+	d.m.Lock()
+	scopes := make([]*Container, 0, len(d.scopes))
+	for _, scope := range d.scopes {
+		scopes = append(scopes, scope)
+	}
+	d.m.Unlock()
+	return scopes
+}
+
+// Returns true when the container is closed.
+func (d *disposables) isClosed() bool {
+	d.m.Lock()
+	closed := d.closed
+	d.m.Unlock()
+	return closed
+}
+
+// track an instance for disposal when it implements Disposer or io.Closer.
+//
+// Returns false when the container is closed. The instance isn't tracked in that case.
+func (d *disposables) track(instance interface{}) bool {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if d.closed {
+		return false
+	}
+	if instance != nil {
+		d.instances = append(d.instances, instance)
+	}
+	return true
+}
+
+// untrack stops tracking an instance, e.g. to dispose a replaced instance before the container is closed.
+//
+// Returns false when the instance isn't tracked or the container is closed.
+func (d *disposables) untrack(instance interface{}) bool {
+	if instance == nil || !reflect.TypeOf(instance).Comparable() {
+		return false
+	}
+	d.m.Lock()
+	defer d.m.Unlock()
+	for i, tracked := range d.instances {
+		if reflect.TypeOf(tracked).Comparable() && tracked == instance {
+			d.instances = append(d.instances[:i], d.instances[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// close marks the container as closed and returns the tracked instances in reverse order of creation.
+//
+// Returns false when the container was already closed.
+func (d *disposables) close() ([]interface{}, bool) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	if d.closed {
+		return nil, false
+	}
+	d.closed = true
+	instances := make([]interface{}, len(d.instances))
+	for i, instance := range d.instances {
+		instances[len(instances)-1-i] = instance
+	}
+	d.instances = nil
+	return instances, true
+}
+
+// disposable returns the Disposer or io.Closer implementation of an instance.
+//
+// The address of the instance is checked first, because instances are stored as non-pointer values.
+//
+// Returns nil when the instance doesn't implement Disposer or io.Closer.
+func disposable(instance *reflect.Value) interface{} {
+	candidates := make([]reflect.Value, 0, 2)
+	if instance.CanAddr() {
+		candidates = append(candidates, instance.Addr())
+	}
+	candidates = append(candidates, *instance)
+	for _, candidate := range candidates {
+		if !candidate.CanInterface() {
+			continue
+		}
+		switch v := candidate.Interface().(type) {
+		case Disposer:
+			return v
+		case io.Closer:
+			return v
+		}
+	}
+	return nil
+}
+
+// dispose calls Dispose(ctx) or Close() on an instance.
+func dispose(ctx context.Context, instance interface{}) error {
+	switch v := instance.(type) {
+	case Disposer:
+		return v.Dispose(ctx)
+	case io.Closer:
+		return v.Close()
+	}
+	return nil
+}
+
+//-----------------------------------------------
+// container
+//-----------------------------------------------
+
+// Own transfers the ownership of an instance to the container, to dispose the instance when the container is closed.
+//
+// Instances created by the container are owned by the container, while instances
+// registered using RegisterInstance/RegisterNamedInstance or set on the container Values aren't,
+// unless ownership is transferred using Own. (see also the Owned option)
+//
+// Own is a no-op when the instance doesn't implement Disposer or io.Closer.
+//
+// Returns an error when:
+//	- The instance type is nil.
+//	- The instance is a nil pointer or interface.
+//	- The container is closed.
+func (c *Container) Own(v interface{}) error {
+	instance, err := GetNamedInstance(v, "")
+	if err != nil {
+		return err
+	}
+	if !c.track(disposable(instance)) {
+		return errContainerClosed(instance.Type(), "")
+	}
+	return nil
+}
+
+// track an instance owned by the container for disposal when it implements Disposer or io.Closer.
+//
+// Returns false when the container or an ancestor is closed. The instance isn't tracked in that case.
+func (c *Container) track(instance interface{}) bool {
+	if instance != nil && !c.attach() {
+		return false
+	}
+	if c.isClosed() {
+		return false
+	}
+	return c.d.track(instance)
+}
+
+// attach tracks a scoped container as an open child scope of its ancestors,
+// so that the scoped container is closed when an ancestor is closed.
+//
+// Returns false when an ancestor is closed.
+func (c *Container) attach() bool {
+	var chain []*Container
+	for x := c; x.parent != nil; x = x.parent {
+		chain = append(chain, x)
+	}
+	// attach the ancestors first, so that a scope is never tracked by a container missed by the closing ancestors
+	for i := len(chain) - 1; i >= 0; i-- {
+		if !chain[i].parent.d.addScope(chain[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Close the container.
+//
+// Close calls CloseContext(context.Background()).
+func (c *Container) Close() error {
+	return c.CloseContext(context.Background())
+}
+
+// Close the container.
+//
+// CloseContext closes the open child scopes owning instances or registrations and then disposes every instance owned by the container in reverse order of creation,
+// by calling Dispose(ctx) on instances implementing Disposer or Close() on instances implementing io.Closer.
+//
+// A scoped container owns the Per Scope instances it creates, and the Per Request instances
+// created through the scope when the scope was created using the OwnPerRequest option.
+// Closing a scoped container doesn't dispose the instances owned by the root container
+// and discards the registrations made on the scoped container.
+//
+// After the container is closed, resolving an instance from the container returns an error with the ErrContainerClosed error code.
+// Calling CloseContext on a closed container is a no-op.
+//
+// Returns an error when one or more instances returned an error when disposed.
+// Every error is reported in one error. (see (*Error).Inner)
+func (c *Container) CloseContext(ctx context.Context) error {
+	// mark the container as closed first, so that no child scope is added after the open scopes are closed
+	instances, ok := c.d.close()
+	if !ok {
+		return nil
+	}
+	var errs []error
+	// close the child scopes before the parent instances are disposed
+	for _, scope := range c.d.getScopes() {
+		if err := scope.CloseContext(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.parent != nil {
+		c.parent.d.removeScope(c)
+		// discard the registrations made on the scoped container
+		c.r.clear()
+	}
+	for _, instance := range instances {
+		if err := dispose(ctx, instance); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errDispose(errors.Join(errs...))
+	}
+	return nil
+}
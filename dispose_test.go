@@ -0,0 +1,234 @@
+package ioc
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// to test
+// Close (CloseContext)
+// - reverse order of creation
+// - Disposer and io.Closer instances
+// - owned instances (Own, Owned)
+// - aggregated errors
+// - resolve after close
+// - scopes (PerScope, OwnPerRequest, cascade)
+
+type closerTestStruct struct {
+	name   string
+	closed *[]string
+	err    error
+}
+
+func (v *closerTestStruct) Close() error {
+	*v.closed = append(*v.closed, v.name)
+	return v.err
+}
+
+type disposerTestStruct struct {
+	closerTestStruct
+	ctx context.Context
+}
+
+func (v *disposerTestStruct) Dispose(ctx context.Context) error {
+	v.ctx = ctx
+	return v.Close()
+}
+
+type disposeTestKey struct{}
+
+// scopingDisposer creates a scope from a container when disposed.
+type scopingDisposer struct {
+	c     *Container
+	scope *Container
+}
+
+func (v *scopingDisposer) Dispose(context.Context) error {
+	v.scope = v.c.Scope()
+	return nil
+}
+
+var _ = Describe("Dispose", func() {
+	var (
+		container *Container
+		closed    []string
+	)
+	BeforeEach(func() {
+		container = NewContainer()
+		closed = nil
+	})
+
+	It("should dispose the created instances in reverse order of creation", func() {
+		MustRegister(container, func(Factory) (*closerTestStruct, error) {
+			return &closerTestStruct{name: "a", closed: &closed}, nil
+		})
+		MustRegister(container, func(factory Factory) (*disposerTestStruct, error) {
+			if _, err := ResolveAs[*closerTestStruct](factory); err != nil {
+				return nil, err
+			}
+			return &disposerTestStruct{closerTestStruct: closerTestStruct{name: "b", closed: &closed}}, nil
+		})
+		MustResolveAs[*disposerTestStruct](container)
+		Expect(closed).To(BeEmpty())
+		Expect(container.Close()).To(BeNil())
+		Expect(closed).To(Equal([]string{"b", "a"}))
+		// closing a closed container is a no-op
+		Expect(container.Close()).To(BeNil())
+		Expect(closed).To(HaveLen(2))
+	})
+	It("should pass the context to Dispose", func() {
+		var d *disposerTestStruct
+		MustRegister(container, func(Factory) (*disposerTestStruct, error) {
+			d = &disposerTestStruct{closerTestStruct: closerTestStruct{name: "d", closed: &closed}}
+			return d, nil
+		})
+		MustResolveAs[*disposerTestStruct](container)
+		ctx := context.WithValue(context.Background(), disposeTestKey{}, "value")
+		Expect(container.CloseContext(ctx)).To(BeNil())
+		Expect(d.ctx).To(Equal(ctx))
+	})
+	It("should not dispose per request or registered instances unless owned", func() {
+		MustRegister(container, func(Factory) (*closerTestStruct, error) {
+			return &closerTestStruct{name: "a", closed: &closed}, nil
+		}, WithLifetime(PerRequest))
+		MustResolveAs[*closerTestStruct](container)
+		container.MustRegisterNamedInstance(&closerTestStruct{name: "b", closed: &closed}, "b")
+		MustRegisterInstance(container, &closerTestStruct{name: "c", closed: &closed}, WithName("c"), Owned())
+		Expect(container.Own(&closerTestStruct{name: "d", closed: &closed})).To(BeNil())
+		Expect(container.Close()).To(BeNil())
+		Expect(closed).To(Equal([]string{"d", "c"}))
+	})
+	It("should dispose the per scope instances created by a scope on close", func() {
+		MustRegister(container, func(Factory) (*closerTestStruct, error) {
+			return &closerTestStruct{name: "root", closed: &closed}, nil
+		})
+		MustRegister(container, func(Factory) (*disposerTestStruct, error) {
+			return &disposerTestStruct{closerTestStruct: closerTestStruct{name: "scope", closed: &closed}}, nil
+		}, WithLifetime(PerScope))
+		scopedContainer := container.Scope()
+		MustResolveAs[*closerTestStruct](scopedContainer)
+		MustResolveAs[*disposerTestStruct](scopedContainer)
+		Expect(scopedContainer.Close()).To(BeNil())
+		Expect(closed).To(Equal([]string{"scope"}))
+		// the root container singletons are still available
+		Expect(MustResolveAs[*closerTestStruct](container).name).To(Equal("root"))
+		Expect(container.Close()).To(BeNil())
+		Expect(closed).To(Equal([]string{"scope", "root"}))
+	})
+	It("should dispose the per request instances created through a scope when owned", func() {
+		MustRegister(container, func(Factory) (*closerTestStruct, error) {
+			return &closerTestStruct{name: "a", closed: &closed}, nil
+		}, WithLifetime(PerRequest))
+		scopedContainer := container.Scope()
+		MustResolveAs[*closerTestStruct](scopedContainer)
+		ownedScope := container.Scope(OwnPerRequest())
+		MustResolveAs[*closerTestStruct](ownedScope)
+		MustResolveAs[*closerTestStruct](ownedScope)
+		Expect(scopedContainer.Close()).To(BeNil())
+		Expect(closed).To(BeEmpty())
+		Expect(ownedScope.Close()).To(BeNil())
+		Expect(closed).To(Equal([]string{"a", "a"}))
+	})
+	It("should close child scopes before the parent", func() {
+		MustRegister(container, func(Factory) (*closerTestStruct, error) {
+			return &closerTestStruct{name: "root", closed: &closed}, nil
+		})
+		names := []string{"parent", "child", "grandchild"}
+		i := 0
+		MustRegister(container, func(Factory) (*disposerTestStruct, error) {
+			name := names[i]
+			i++
+			return &disposerTestStruct{closerTestStruct: closerTestStruct{name: name, closed: &closed}}, nil
+		}, WithLifetime(PerScope))
+		MustResolveAs[*closerTestStruct](container)
+		parent := container.Scope()
+		child := parent.Scope()
+		grandchild := child.Scope()
+		MustResolveAs[*disposerTestStruct](parent)
+		MustResolveAs[*disposerTestStruct](child)
+		MustResolveAs[*disposerTestStruct](grandchild)
+		Expect(parent.Close()).To(BeNil())
+		Expect(closed).To(Equal([]string{"grandchild", "child", "parent"}))
+		_, err := ResolveAs[*disposerTestStruct](grandchild)
+		Expect(err).ToNot(BeNil())
+		Expect(err.(*Error).Code).To(Equal(ErrContainerClosed))
+		// scopes created from a closed container are closed
+		_, err = ResolveAs[*closerTestStruct](parent.Scope())
+		Expect(err).ToNot(BeNil())
+		Expect(container.Close()).To(BeNil())
+		Expect(closed).To(Equal([]string{"grandchild", "child", "parent", "root"}))
+	})
+	It("should only track the scopes owning instances or registrations", func() {
+		child := container.Scope().Scope()
+		Expect(container.d.getScopes()).To(BeEmpty())
+		Expect(child.Own(&closerTestStruct{name: "child", closed: &closed})).To(Succeed())
+		Expect(container.d.getScopes()).To(HaveLen(1))
+		registering := container.Scope()
+		registering.MustRegisterInstance(1)
+		Expect(container.d.getScopes()).To(HaveLen(2))
+		idle := container.Scope()
+		Expect(container.Close()).To(BeNil())
+		Expect(closed).To(Equal([]string{"child"}))
+		Expect(registering.d.isClosed()).To(BeTrue())
+		// an untracked scope can't be used once an ancestor is closed
+		var v int
+		Expect(idle.Resolve(&v)).To(MatchError(ErrContainerClosed))
+		Expect(idle.Own(&closerTestStruct{name: "idle", closed: &closed})).ToNot(Succeed())
+	})
+	It("should close a scope created while the open scopes are closed", func() {
+		parent := container.Scope()
+		disposer := &scopingDisposer{c: parent}
+		Expect(parent.Scope().Own(disposer)).To(Succeed())
+		Expect(parent.Close()).To(BeNil())
+		Expect(disposer.scope).ToNot(BeNil())
+		Expect(disposer.scope.isClosed()).To(BeTrue())
+	})
+	Context("should return an error when", func() {
+		It("instances return an error when disposed", func() {
+			container.MustAdd(func(Factory) (interface{}, error) {
+				return &closerTestStruct{name: "a", closed: &closed, err: fmt.Errorf("a")}, nil
+			}, (**closerTestStruct)(nil), PerContainer)
+			container.MustAdd(func(Factory) (interface{}, error) {
+				return &closerTestStruct{name: "b", closed: &closed, err: fmt.Errorf("b")}, nil
+			}, (**closerTestStruct)(nil), PerContainer)
+			var v []*closerTestStruct
+			container.MustResolveAll(&v)
+			err := container.Close()
+			Expect(err).ToNot(BeNil())
+			Expect(err.(*Error).Code).To(Equal(ErrDispose))
+			Expect(closed).To(Equal([]string{"b", "a"}))
+		})
+		It("resolving from a closed container", func() {
+			container.MustRegisterInstance(1)
+			scopedContainer := container.Scope()
+			Expect(container.Close()).To(BeNil())
+			var v int
+			err := container.Resolve(&v)
+			Expect(err).ToNot(BeNil())
+			Expect(err.(*Error).Code).To(Equal(ErrContainerClosed))
+			err = scopedContainer.Resolve(&v)
+			Expect(err).ToNot(BeNil())
+			Expect(err.(*Error).Code).To(Equal(ErrContainerClosed))
+			_, err = container.ResolveNamedOptional(&v, "missing")
+			Expect(err).ToNot(BeNil())
+		})
+		It("resolving every instance from a closed container", func() {
+			container.MustAdd(func(Factory) (interface{}, error) {
+				return &closerTestStruct{name: "a", closed: &closed}, nil
+			}, (**closerTestStruct)(nil), PerContainer)
+			var v []*closerTestStruct
+			container.MustResolveAll(&v)
+			Expect(container.Close()).To(BeNil())
+			v = nil
+			Expect(container.ResolveAll(&v)).To(MatchError(ErrContainerClosed))
+			Expect(v).To(BeEmpty())
+		})
+		It("owning an instance on a closed container", func() {
+			Expect(container.Close()).To(BeNil())
+			Expect(container.Own(&closerTestStruct{name: "a", closed: &closed})).ToNot(BeNil())
+		})
+	})
+})
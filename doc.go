@@ -7,7 +7,8 @@ Basics
 
 The containers provided by package ioc make use of runtime reflection to detect value types and to resolve an instance by type and name.
 
-Containers can be scoped.
+Containers can be scoped. Registrations made on a scoped container are only visible to the scope and its descendants,
+and are discarded when the scope is closed, e.g. to override a registration in tests or per tenant.
 
 Example:
 	type UserRepository interface {
@@ -53,21 +54,21 @@ Example:
 	// Resolve requires a non-nil pointer, but you can pass a reference to a nil pointer.
 	c.MustResolve(&userRepository)
 
-	// scoped example
+	// scoped example (see the iochttp subpackage for net/http integration)
 	func ContainerMiddleware(next http.Handler) http.Handler {
 		return http.HandlerFunc(func (w http.ResponseWriter, r *http.Request) {
 			scopedContainer := c.Scope()
+			defer scopedContainer.Close()
 			scopedContainer.MustSet(&w)
 			scopedContainer.MustSet(r)
-			// use the scopedContainer within the request scope, e.g. using gorilla
-			context.Set(r, "container", scopedContainer)
+			// use the scopedContainer within the request scope
+			next.ServeHTTP(w, r.WithContext(ioc.WithContainer(r.Context(), scopedContainer)))
 		})
 	}
 
 	func DoSomething(w http.ResponseWriter, r *http.Request) {
-		container := context.Get(r, "container").(*Container)
-		var userRepository UserRepository
-		container.MustGet(&userRepository)
+		// or ioc.FromContext(r.Context()) to get the scoped container
+		userRepository, err := ioc.ResolveAs[UserRepository](ioc.FactoryFromContext(r.Context()))
 		user, err := userRepository.GetById(1)
 	}
 
@@ -77,17 +78,57 @@ The following methods can be used to resolve instances:
 	- (*ioc.Values) Get/GetNamed
 	- (ioc.Factory) ResolveNamed
 	- ioc.Resolve/ioc.ResolveNamed
+	- ioc.ResolveOptional (returns false instead of an error when the instance isn't registered or found)
+	- (*ioc.Container) ResolveNamedContext (passes the context to the instance factories, see ioc.ContextFactory)
+	- (*ioc.Container) WithResolution (resolves several instances within one resolution, see ioc.PerResolve)
 
 Resolved instances are stored in the value pointed to by v.
 
 	A non-nil pointer or a reference to a nil-pointer is required to set the value pointed to by v.
 
+Resolve errors report the resolution path from the instance being resolved to the instance that caused the error
+(see (*ioc.Error).Path), e.g.
+	ioc.Service -> ioc.Repository("primary") -> sql.DB: ioc: (*Container).Resolve: instance of type "sql.DB" can't be resolved.
+
+When an instance can't be resolved or found, the error lists the near matches (see (*ioc.Error).Suggestions):
+the same type under another name, types implementing the interface type and types with the same name in another package.
+
+Every ErrorCode is a sentinel error matched with errors.Is, e.g. errors.Is(err, ioc.ErrNotFound).
+The error returned by an instance factory function is matched by errors.Is and errors.As. (see (*ioc.Error).Unwrap)
+
+Disposal
+
+(*ioc.Container) Close/CloseContext disposes every instance owned by the container in reverse order of creation,
+by calling Dispose(ctx) on instances implementing ioc.Disposer or Close() on instances implementing io.Closer.
+
+The container owns the singleton instances it creates. Registered instances are only disposed
+when ownership is transferred using (*ioc.Container) Own or the ioc.Owned option.
+
+A scoped container owns the Per Scope instances it creates, and the Per Request instances created through the scope
+when the scope is created using the ioc.OwnPerRequest option. Open child scopes are closed before their parent.
+
+Resolving an instance from a closed container returns an error with the ErrContainerClosed error code.
+
+Providers
+
+An instance can be resolved on demand by resolving a provider for its type, unless the provider type is registered:
+	- func() (T, error)
+	- func() T (panics when T can't be resolved)
+	- ioc.Lazy[T] (resolves T on the first call to Value and caches the instance)
+
+Providers resolve from the container scope that resolved the provider and can be used to break construction cycles.
+
+Example:
+	var newUnitOfWork func() (*UnitOfWork, error)
+	c.MustResolve(&newUnitOfWork)
+	uow, err := newUnitOfWork()
+
 Registering Instances
 
 The following methods can be used to register instances:
 	- (*ioc.Values) Set/SetNamed
 	- (*ioc.Container) Set/SetNamed (scoped container singleton/scope vars)
-	- (*ioc.Container) RegisterInstance/RegisterNamedInstance (root container singleton)
+	- (*ioc.Container) RegisterInstance/RegisterNamedInstance (container singleton)
 
 	The instance being registered can't be a nil pointer or interface.
 
@@ -109,6 +150,7 @@ Instance Factory Registrations
 
 The following methods can be used to register an instance factory:
 	- (*ioc.Container) Register/RegisterNamed (instance factory)
+	- (*ioc.Container) RegisterContext/RegisterNamedContext (instance factory receiving the context of the request)
 
 An instance factory function must return a non-nil value or an error.
 
@@ -119,6 +161,113 @@ The Lifetime characteristics determines how a resolved instance is reused:
 	- Per Container Lifetime requires that an instance is only created once per container.
 	- Per Scope lifetime requires that an instance is only created once per scope.
 	- Per Request lifetime requires that a new instance is created on every request.
+	- Pooled lifetime hands out an instance from a pool on every request and returns the instance to the pool
+	  when the requesting scope is closed, after calling Reset() on instances implementing ioc.Resetter.
+	  An instance requested from the root container isn't returned to the pool.
+	- Per Resolve lifetime requires that an instance is only created once per resolution,
+	  i.e. the instance is shared by the dependencies of one top-level resolve call, e.g. a unit of work.
+
+ioc.PerContainerTTL registers a lifetime requiring that an instance is created once per container and re-created once expired,
+optionally serving the expired instance while the instance is re-created. (see ioc.ServeStale and ioc.WithClock)
+
+Example:
+	tokenLifetime := ioc.PerContainerTTL(5*time.Minute, ioc.ServeStale())
+	c.MustRegister(newOAuthToken, (*OAuthToken)(nil), tokenLifetime)
+
+ioc.PerNamedScope returns a lifetime requiring that an instance is only created once per named scope,
+by caching the instance on the nearest scope created using (*ioc.Container) ScopeNamed with the name.
+
+Example:
+	c.MustRegister(newTenantCache, (*TenantCache)(nil), ioc.PerNamedScope("tenant"))
+	tenantScope := c.ScopeNamed("tenant")
+	requestScope := tenantScope.Scope() // resolves the TenantCache cached on tenantScope
+
+Custom lifetimes are implemented by an ioc.LifetimeManager and registered using ioc.RegisterLifetime.
+
+Decorators
+
+The following methods can be used to wrap the instances created by instance factories:
+	- (*ioc.Container) Decorate/DecorateNamed
+	- ioc.Decorate
+
+Decorators are applied in the order they were added, before an instance is cached according to the lifetime,
+and apply to registrations made after the decorator was added.
+
+Example:
+	ioc.MustDecorate(c, func(factory ioc.Factory, repo UserRepository) (UserRepository, error) {
+		return &loggingUserRepository{next: repo}, nil
+	})
+
+Multi Registrations
+
+The following methods can be used to add one of many instance factories for a type and name:
+	- (*ioc.Container) Add/AddNamed
+
+Every added instance is resolved in the order it was added, honoring the lifetime of each registration,
+by passing a pointer to a slice to:
+	- (*ioc.Container) ResolveAll/ResolveAllNamed
+	- (ioc.MultiFactory) ResolveAllNamed
+	- ioc.ResolveAll/ioc.ResolveAllNamed
+
+Example:
+	c.MustAdd(newDatabaseHealthChecker, (*HealthChecker)(nil), ioc.PerContainer)
+	c.MustAdd(newCacheHealthChecker, (*HealthChecker)(nil), ioc.PerContainer)
+	var checkers []HealthChecker
+	c.MustResolveAll(&checkers)
+
+Every named instance of a type is resolved by passing a pointer to a map with a string key type to:
+	- (*ioc.Container) ResolveNamedMap
+	- (ioc.MapFactory) ResolveNamedMap
+	- ioc.ResolveNamedMap
+
+Example:
+	var shards map[string]*sql.DB
+	c.MustResolveNamedMap(&shards)
+
+Constructor Registrations
+
+(*ioc.Container) RegisterConstructor registers an ordinary constructor function.
+The constructor parameters are resolved by type and the return type is registered, unless set with the As option.
+
+Example:
+	c.MustRegisterConstructor(newPostgresUserRepository, ioc.PerContainer, ioc.As((*UserRepository)(nil)))
+	// ensure every constructor dependency can be resolved
+	if err := c.Validate(); err != nil {
+		panic(err)
+	}
+
+Populating Structs
+
+ioc.Populate uses a Factory to set struct fields tagged with `ioc:"name[,optional]"`.
+
+Example:
+	type UserHandler struct {
+		Users UserRepository `ioc:""`
+		DB    *sql.DB        `ioc:"primary"`
+		Log   Logger         `ioc:",optional"`
+	}
+
+	var handler UserHandler
+	ioc.MustPopulate(c, &handler)
+
+Fields tagged with `ioc_<source>:"name"` are resolved from the Factory named <source>,
+e.g. `ioc_route:"id"` resolves the Factory named "route" and calls ResolveNamed(field, "id") on it.
+
+Generic Registrations
+
+The generic functions derive the implementing type from the type parameter:
+	- ioc.Register/ioc.RegisterInstance (options: WithName, WithLifetime)
+	- ioc.ResolveAs/ioc.ResolveNamedAs
+
+Example:
+	ioc.MustRegister[UserRepository](c, func(factory ioc.Factory) (UserRepository, error) {
+		db, err := ioc.ResolveAs[*sql.DB](factory)
+		if err != nil {
+			return nil, err
+		}
+		return newPostgresUserRepository(db), nil
+	}, ioc.WithLifetime(ioc.PerContainer))
+	userRepository := ioc.MustResolveAs[UserRepository](c)
 
 */
 package ioc
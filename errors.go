@@ -2,6 +2,7 @@ package ioc
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"path"
 	"reflect"
@@ -19,7 +20,8 @@ const (
 	// (e.g. called GetNamedType(v:nil, name:"").
 	ErrNilType
 	// ErrCreateInstanceNil is raised
-	//   by (*Container).RegisterNamed when createInstance is nil or
+	//   by (*Container).RegisterNamed when createInstance is nil,
+	//   by (*Container).DecorateNamed when decorate is nil or
 	//   by (*Registration).CreateInstance when (*Registration).CreateInstanceFn is nil.
 	ErrCreateInstanceNil
 	// ErrCreateInstance is raised by (*Registration).CreateInstance when (*Registration).CreateInstanceFn
@@ -46,11 +48,97 @@ const (
 	// ErrRequirePointer is raised by GetNamedSetter, GetNamedType when v isn't a pointer.
 	ErrRequirePointer
 	// ErrResolveInfiniteRecursion is raised by (*dependencyResolver).ResolveNamed
-	// when the count of resolve by type and name within a (*Container).ResolveNamed call
-	// exceeds the RecursionLimit.
+	// when an instance is resolved while it's being created. (a dependency cycle)
 	ErrResolveInfiniteRecursion
+	// ErrInvalidConstructor is raised by (*Container).RegisterConstructor
+	// when the constructor isn't a function or has an unsupported signature.
+	ErrInvalidConstructor
+	// ErrRequireStruct is raised by Populate when the target isn't a pointer to a struct.
+	ErrRequireStruct
+	// ErrPopulate is raised by Populate when one or more tagged fields can't be set.
+	ErrPopulate
+	// ErrRequireSlice is raised by ResolveAllNamed when v isn't a pointer to a slice.
+	ErrRequireSlice
+	// ErrUnsupportedFactory is raised by the Factory helpers
+	// when the factory doesn't support the operation. (e.g. ResolveAll on Values)
+	ErrUnsupportedFactory
+	// ErrRequireMap is raised by ResolveNamedMap when v isn't a pointer to a map with a string key type.
+	ErrRequireMap
+	// ErrContainerClosed is raised by (*dependencyResolver).ResolveNamed and (*Container).Own
+	// when the container is closed.
+	ErrContainerClosed
+	// ErrDispose is raised by (*Container).Close when one or more instances returned an error when disposed.
+	ErrDispose
+	// ErrContextDone is raised by (*dependencyResolver).ResolveNamed when the context of the request is done.
+	// The error wraps ctx.Err().
+	ErrContextDone
+	// ErrNoContainer is raised by the Factory returned by FactoryFromContext
+	// when the context doesn't carry a container.
+	ErrNoContainer
+	// ErrScopeNotFound is raised by (*dependencyResolver).ResolveNamed when an instance with the PerNamedScope lifetime
+	// is resolved from a container without an ancestor scope with the name.
+	ErrScopeNotFound
 )
 
+const (
+	// ErrNotFound matches the ErrInstanceNotFound and ErrUnresolvedDependency error codes using errors.Is.
+	ErrNotFound ErrorCode = -1
+	// ErrCycle is an alias of ErrResolveInfiniteRecursion.
+	ErrCycle = ErrResolveInfiniteRecursion
+)
+
+var errorCodeNames = [...]string{
+	ErrInstanceNotFound:         "ErrInstanceNotFound",
+	ErrNilType:                  "ErrNilType",
+	ErrCreateInstanceNil:        "ErrCreateInstanceNil",
+	ErrCreateInstance:           "ErrCreateInstance",
+	ErrUnresolvedDependency:     "ErrUnresolvedDependency",
+	ErrUnsupportedLifetime:      "ErrUnsupportedLifetime",
+	ErrUnexpectedValueType:      "ErrUnexpectedValueType",
+	ErrInterfaceNotImplemented:  "ErrInterfaceNotImplemented",
+	ErrNilValue:                 "ErrNilValue",
+	ErrNonSetNilPointer:         "ErrNonSetNilPointer",
+	ErrRequirePointer:           "ErrRequirePointer",
+	ErrResolveInfiniteRecursion: "ErrResolveInfiniteRecursion",
+	ErrInvalidConstructor:       "ErrInvalidConstructor",
+	ErrRequireStruct:            "ErrRequireStruct",
+	ErrPopulate:                 "ErrPopulate",
+	ErrRequireSlice:             "ErrRequireSlice",
+	ErrUnsupportedFactory:       "ErrUnsupportedFactory",
+	ErrRequireMap:               "ErrRequireMap",
+	ErrContainerClosed:          "ErrContainerClosed",
+	ErrDispose:                  "ErrDispose",
+	ErrContextDone:              "ErrContextDone",
+	ErrNoContainer:              "ErrNoContainer",
+	ErrScopeNotFound:            "ErrScopeNotFound",
+}
+
+// String returns the name of the error code.
+func (code ErrorCode) String() string {
+	if code == ErrNotFound {
+		return "ErrNotFound"
+	}
+	if code >= 0 && int(code) < len(errorCodeNames) {
+		return errorCodeNames[code]
+	}
+	return fmt.Sprintf("ErrorCode(%d)", int(code))
+}
+
+// Error implements the error interface, so that an error code can be used as a sentinel error with errors.Is.
+//
+// e.g. errors.Is(err, ioc.ErrNotFound)
+func (code ErrorCode) Error() string {
+	return "ioc: " + code.String()
+}
+
+// matches returns true when the error code matches the code of an error.
+func (code ErrorCode) matches(other ErrorCode) bool {
+	if code == ErrNotFound {
+		return other == ErrInstanceNotFound || other == ErrUnresolvedDependency
+	}
+	return code == other
+}
+
 type Error struct {
 	Type      reflect.Type
 	Name      string
@@ -61,10 +149,23 @@ type Error struct {
 	File      string
 	LineNo    int
 	Method    string
+	// Path is the resolution path of a resolve error, from the instance being resolved
+	// to the instance that caused the error.
+	Path []ResolveStep
+	// Suggestions are the near matches for an instance that can't be resolved or found.
+	Suggestions []Suggestion
 }
 
 func (e *Error) Error() string {
+	// the inner error of a dependency reports the full resolution path
+	if inner, ok := e.Inner.(*Error); ok && len(e.Path) > 0 && len(inner.Path) == len(e.Path) {
+		return inner.Error()
+	}
 	var b bytes.Buffer
+	if len(e.Path) > 1 {
+		b.WriteString(formatPath(e.Path))
+		b.WriteString(": ")
+	}
 	b.WriteString(e.Message)
 	if e.Inner != nil {
 		b.WriteRune('\n')
@@ -73,8 +174,49 @@ func (e *Error) Error() string {
 	return b.String()
 }
 
+// Unwrap returns the inner error.
+func (e *Error) Unwrap() error {
+	return e.Inner
+}
+
+// Is returns true when the target is an error code matching the error code. (see ErrNotFound)
+func (e *Error) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && code.matches(e.Code)
+}
+
+// ResolveStep is an instance on the resolution path of a resolve error.
+type ResolveStep struct {
+	Type reflect.Type
+	Name string
+	// Lifetime is the lifetime of the registration. (only set when Registered is true)
+	Lifetime Lifetime
+	// Registered is false when the instance isn't created from a registration, e.g. a missing instance.
+	Registered bool
+	// Scope is the depth of the container scope resolving the instance. (0 for the root container)
+	Scope int
+	id    uint64
+}
+
+// String returns the type and name of the instance, e.g. ioc.Repository("primary").
+func (step ResolveStep) String() string {
+	if step.Name != "" {
+		return fmt.Sprintf("%s(%q)", step.Type, step.Name)
+	}
+	return fmt.Sprint(step.Type)
+}
+
+// formatPath formats a resolution path, e.g. ioc.Service -> ioc.Repository("primary") -> sql.DB
+func formatPath(path []ResolveStep) string {
+	steps := make([]string, len(path))
+	for i, step := range path {
+		steps[i] = step.String()
+	}
+	return strings.Join(steps, " -> ")
+}
+
 // callers: values.go
-func errInstanceNotFound(typ reflect.Type, name string) error {
+func errInstanceNotFound(typ reflect.Type, name string, suggestions []Suggestion) error {
 	method, callingMethod, file, lineNo := getCaller()
 	var b bytes.Buffer
 	b.WriteString(fmt.Sprintf("ioc: %s: ", method))
@@ -84,14 +226,16 @@ func errInstanceNotFound(typ reflect.Type, name string) error {
 		b.WriteString("instance ")
 	}
 	b.WriteString(fmt.Sprintf("of type \"%s\" not found.", typ))
+	writeSuggestions(&b, suggestions)
 	return &Error{
-		Type:    typ,
-		Name:    name,
-		Code:    ErrInstanceNotFound,
-		Message: b.String(),
-		File:    file,
-		LineNo:  lineNo,
-		Method:  callingMethod,
+		Type:        typ,
+		Name:        name,
+		Code:        ErrInstanceNotFound,
+		Message:     b.String(),
+		Suggestions: suggestions,
+		File:        file,
+		LineNo:      lineNo,
+		Method:      callingMethod,
 	}
 }
 
@@ -136,7 +280,29 @@ func errCreateInstanceFnNil(typ reflect.Type, name string) error {
 	}
 }
 
-// callers: container.go, registry.go
+// callers: container.go
+func errDecorateFnNil(typ reflect.Type, name string) error {
+	method, callingMethod, file, lineNo := getCaller()
+	var b bytes.Buffer
+	b.WriteString(fmt.Sprintf("ioc: %s: decorator function is nil. unable to decorate ", method))
+	if name != "" {
+		b.WriteString(fmt.Sprintf("a named instance \"%s\" ", name))
+	} else {
+		b.WriteString("an instance ")
+	}
+	b.WriteString(fmt.Sprintf("of type \"%s\".", typ))
+	return &Error{
+		Type:    typ,
+		Name:    name,
+		Code:    ErrCreateInstanceNil,
+		Message: b.String(),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
+// callers: container.go, lifetime.go, registry.go
 func errCreateInstance(typ reflect.Type, name string, err error) error {
 	method, callingMethod, file, lineNo := getCaller()
 	var b bytes.Buffer
@@ -160,7 +326,7 @@ func errCreateInstance(typ reflect.Type, name string, err error) error {
 }
 
 // callers: dependency_resolver.go
-func errUnresolvedDependency(typ reflect.Type, name string) error {
+func errUnresolvedDependency(typ reflect.Type, name string, suggestions []Suggestion) error {
 	method, callingMethod, file, lineNo := getCaller()
 	var b bytes.Buffer
 	b.WriteString(fmt.Sprintf("ioc: %s: ", method))
@@ -170,14 +336,16 @@ func errUnresolvedDependency(typ reflect.Type, name string) error {
 		b.WriteString("instance ")
 	}
 	b.WriteString(fmt.Sprintf("of type \"%s\" can't be resolved.", typ))
+	writeSuggestions(&b, suggestions)
 	return &Error{
-		Type:    typ,
-		Name:    name,
-		Code:    ErrUnresolvedDependency,
-		Message: b.String(),
-		File:    file,
-		LineNo:  lineNo,
-		Method:  callingMethod,
+		Type:        typ,
+		Name:        name,
+		Code:        ErrUnresolvedDependency,
+		Message:     b.String(),
+		Suggestions: suggestions,
+		File:        file,
+		LineNo:      lineNo,
+		Method:      callingMethod,
 	}
 }
 
@@ -314,7 +482,7 @@ func errRequirePointer(typ reflect.Type, name string) error {
 }
 
 // callers: dependency_resolver.go
-func errResolveInfiniteRecursion(typ reflect.Type, name string) error {
+func errResolveInfiniteRecursion(typ reflect.Type, name string, cycle string) error {
 	method, callingMethod, file, lineNo := getCaller()
 	var b bytes.Buffer
 	b.WriteString(fmt.Sprintf("ioc: %s: infinite recursion detected. ", method))
@@ -323,7 +491,7 @@ func errResolveInfiniteRecursion(typ reflect.Type, name string) error {
 	} else {
 		b.WriteString("instance ")
 	}
-	b.WriteString(fmt.Sprintf("of type \"%s\" can't be resolved.", typ))
+	b.WriteString(fmt.Sprintf("of type \"%s\" can't be resolved. (%s)", typ, cycle))
 	return &Error{
 		Type:    typ,
 		Name:    name,
@@ -335,10 +503,250 @@ func errResolveInfiniteRecursion(typ reflect.Type, name string) error {
 	}
 }
 
+// callers: constructor.go, container.go
+func errInvalidConstructor(typ reflect.Type, name string, reason string) error {
+	method, callingMethod, file, lineNo := getCaller()
+	var b bytes.Buffer
+	b.WriteString(fmt.Sprintf("ioc: %s: ", method))
+	if name != "" {
+		b.WriteString(fmt.Sprintf("\"%s\" ", name))
+	}
+	b.WriteString(fmt.Sprintf("constructor of type \"%s\" %s.", typ, reason))
+	return &Error{
+		Type:    typ,
+		Name:    name,
+		Code:    ErrInvalidConstructor,
+		Message: b.String(),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
+// callers: container.go
+func errMissingDependency(typ reflect.Type, name string, dependentType reflect.Type, dependentName string) error {
+	method, callingMethod, file, lineNo := getCaller()
+	var b bytes.Buffer
+	b.WriteString(fmt.Sprintf("ioc: %s: ", method))
+	if name != "" {
+		b.WriteString(fmt.Sprintf("named instance \"%s\" ", name))
+	} else {
+		b.WriteString("instance ")
+	}
+	b.WriteString(fmt.Sprintf("of type \"%s\" required by ", typ))
+	if dependentName != "" {
+		b.WriteString(fmt.Sprintf("named instance \"%s\" ", dependentName))
+	} else {
+		b.WriteString("instance ")
+	}
+	b.WriteString(fmt.Sprintf("of type \"%s\" can't be resolved.", dependentType))
+	return &Error{
+		Type:      typ,
+		Name:      name,
+		OtherType: dependentType,
+		Code:      ErrUnresolvedDependency,
+		Message:   b.String(),
+		File:      file,
+		LineNo:    lineNo,
+		Method:    callingMethod,
+	}
+}
+
+// callers: populate.go
+func errRequireStruct(typ reflect.Type) error {
+	method, callingMethod, file, lineNo := getCaller()
+	return &Error{
+		Type:    typ,
+		Code:    ErrRequireStruct,
+		Message: fmt.Sprintf("ioc: %s: value of type \"%s\" must be a non-nil pointer to a struct.", method, typ),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
+// callers: populate.go
+func errPopulate(typ reflect.Type, err error) error {
+	method, callingMethod, file, lineNo := getCaller()
+	return &Error{
+		Type:    typ,
+		Code:    ErrPopulate,
+		Inner:   err,
+		Message: fmt.Sprintf("ioc: %s: unable to populate the fields of type \"%s\".", method, typ),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
+// callers: dependency_resolver.go
+func errRequireSlice(typ reflect.Type, name string) error {
+	method, callingMethod, file, lineNo := getCaller()
+	var b bytes.Buffer
+	b.WriteString(fmt.Sprintf("ioc: %s: ", method))
+	if name != "" {
+		b.WriteString(fmt.Sprintf("\"%s\" ", name))
+	}
+	b.WriteString(fmt.Sprintf("value of type \"%s\" ", typ))
+	b.WriteString("must be a non-nil pointer to a slice.")
+	return &Error{
+		Type:    typ,
+		Name:    name,
+		Code:    ErrRequireSlice,
+		Message: b.String(),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
+// callers: dependency_resolver.go, values.go
+func errRequireMap(typ reflect.Type) error {
+	method, callingMethod, file, lineNo := getCaller()
+	return &Error{
+		Type:    typ,
+		Code:    ErrRequireMap,
+		Message: fmt.Sprintf("ioc: %s: value of type \"%s\" must be a non-nil pointer to a map with a string key type.", method, typ),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
+// callers: dependency_resolver.go, dispose.go, lifetime.go, pool.go
+func errContainerClosed(typ reflect.Type, name string) error {
+	method, callingMethod, file, lineNo := getCaller()
+	var b bytes.Buffer
+	b.WriteString(fmt.Sprintf("ioc: %s: container is closed. unable to resolve ", method))
+	if name != "" {
+		b.WriteString(fmt.Sprintf("a named instance \"%s\" ", name))
+	} else {
+		b.WriteString("an instance ")
+	}
+	b.WriteString(fmt.Sprintf("of type \"%s\".", typ))
+	return &Error{
+		Type:    typ,
+		Name:    name,
+		Code:    ErrContainerClosed,
+		Message: b.String(),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
+// callers: dependency_resolver.go, dispose.go
+func errDispose(err error) error {
+	method, callingMethod, file, lineNo := getCaller()
+	return &Error{
+		Code:    ErrDispose,
+		Inner:   err,
+		Message: fmt.Sprintf("ioc: %s: unable to dispose one or more instances.", method),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
+// callers: dependency_resolver.go, lifetime.go, ttl.go
+func errContextDone(typ reflect.Type, name string, err error) error {
+	method, callingMethod, file, lineNo := getCaller()
+	var b bytes.Buffer
+	b.WriteString(fmt.Sprintf("ioc: %s: context is done. unable to resolve ", method))
+	if name != "" {
+		b.WriteString(fmt.Sprintf("a named instance \"%s\" ", name))
+	} else {
+		b.WriteString("an instance ")
+	}
+	b.WriteString(fmt.Sprintf("of type \"%s\".", typ))
+	return &Error{
+		Type:    typ,
+		Name:    name,
+		Code:    ErrContextDone,
+		Inner:   err,
+		Message: b.String(),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
+// callers: context.go
+func errNoContainer() error {
+	method, callingMethod, file, lineNo := getCaller()
+	return &Error{
+		Code:    ErrNoContainer,
+		Message: fmt.Sprintf("ioc: %s: the context doesn't carry a container. (see WithContainer)", method),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
+// callers: lifetime.go
+func errScopeNotFound(typ reflect.Type, name string, scope string) error {
+	method, callingMethod, file, lineNo := getCaller()
+	var b bytes.Buffer
+	b.WriteString(fmt.Sprintf("ioc: %s: no scope named \"%s\" found. unable to resolve ", method, scope))
+	if name != "" {
+		b.WriteString(fmt.Sprintf("a named instance \"%s\" ", name))
+	} else {
+		b.WriteString("an instance ")
+	}
+	b.WriteString(fmt.Sprintf("of type \"%s\" from the container or an ancestor. (see ScopeNamed)", typ))
+	return &Error{
+		Type:    typ,
+		Name:    name,
+		Code:    ErrScopeNotFound,
+		Message: b.String(),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
+// callers: helpers.go
+func errUnsupportedFactory(factory Factory, operation string) error {
+	method, callingMethod, file, lineNo := getCaller()
+	typ := reflect.TypeOf(factory)
+	return &Error{
+		Type:    typ,
+		Code:    ErrUnsupportedFactory,
+		Message: fmt.Sprintf("ioc: %s: factory of type \"%s\" doesn't support %s.", method, typ, operation),
+		File:    file,
+		LineNo:  lineNo,
+		Method:  callingMethod,
+	}
+}
+
 //-----------------------------------------------
 // helpers
 //-----------------------------------------------
 
+// writeSuggestions appends the suggestions to an error message.
+func writeSuggestions(b *bytes.Buffer, suggestions []Suggestion) {
+	if len(suggestions) == 0 {
+		return
+	}
+	b.WriteString(" did you mean ")
+	for i, suggestion := range suggestions {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(suggestion.String())
+	}
+	b.WriteRune('?')
+}
+
+// isNotFound returns true when the error was raised because an instance isn't registered or found.
+func isNotFound(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return ErrNotFound.matches(e.Code)
+}
+
 var pkgName = reflect.TypeOf(Values{}).PkgPath()
 
 func getCaller() (method, callingMethod, file string, lineNo int) {
@@ -1,5 +1,7 @@
 package ioc
 
+import "context"
+
 // Factory represents a container able to
 // resolve instances by type and name.
 //
@@ -7,7 +9,63 @@ package ioc
 //	- Values
 //	- Container
 //	- dependencyResolver (internal)
+//	- FactoryFromContext
 type Factory interface {
 	// Resolve a named instance by type.
 	ResolveNamed(v interface{}, name string) error
 }
+
+// MultiFactory represents a container able to
+// resolve every instance added for a type and name.
+//
+// Implemented by:
+//	- Container
+//	- dependencyResolver (internal)
+//	- FactoryFromContext
+type MultiFactory interface {
+	Factory
+	// Resolve every instance added for a type and name.
+	ResolveAllNamed(v interface{}, name string) error
+}
+
+// MapFactory represents a container able to
+// resolve every named instance of a type.
+//
+// Implemented by:
+//	- Values
+//	- Container
+//	- dependencyResolver (internal)
+//	- FactoryFromContext
+type MapFactory interface {
+	Factory
+	// Resolve every named instance of a type into a map by name.
+	ResolveNamedMap(v interface{}) error
+}
+
+// OptionalFactory represents a container able to
+// resolve an optional instance by type and name, without raising an error when the instance isn't found.
+//
+// Implemented by:
+//	- Values
+//	- Container
+//	- dependencyResolver (internal)
+//	- FactoryFromContext
+type OptionalFactory interface {
+	Factory
+	// Resolve an optional named instance by type.
+	ResolveNamedOptional(v interface{}, name string) (bool, error)
+}
+
+// ContextFactory represents a container able to
+// resolve instances by type and name using the context of the request.
+//
+// Implemented by:
+//	- dependencyResolver (internal)
+//	- FactoryFromContext
+type ContextFactory interface {
+	Factory
+	// Context returns the context of the request.
+	Context() context.Context
+	// Resolve a named instance by type using a context.
+	ResolveNamedContext(ctx context.Context, v interface{}, name string) error
+}
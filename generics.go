@@ -0,0 +1,195 @@
+package ioc
+
+import "reflect"
+
+//-----------------------------------------------
+// registry helpers
+//-----------------------------------------------
+
+// Register a typed instance factory on a container.
+//
+// The implementing type is derived from T, e.g. Register[UserRepository] registers the UserRepository interface.
+//
+// Register calls c.RegisterNamed with the name and lifetime set by the options. (default: "", PerContainer)
+func Register[T any](c *Container, createInstance func(Factory) (T, error), opts ...RegisterOption) error {
+	options := newRegisterOptions(opts)
+	var fn func(Factory) (interface{}, error)
+	if createInstance != nil {
+		fn = func(factory Factory) (interface{}, error) {
+			instance, err := createInstance(factory)
+			if err != nil {
+				return nil, err
+			}
+			return instance, nil
+		}
+	}
+	return c.RegisterNamed(fn, (*T)(nil), options.name, options.lifetime)
+}
+
+// Register a typed instance factory on a container.
+//
+// MustRegister calls Register[T](c, createInstance, opts...) and panics if an error is returned.
+func MustRegister[T any](c *Container, createInstance func(Factory) (T, error), opts ...RegisterOption) {
+	if err := Register[T](c, createInstance, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// Register a typed instance on a container.
+//
+// The instance is registered as T, avoiding the need to pass a pointer to an interface value.
+//
+// RegisterInstance calls c.RegisterNamedInstance with the name set by the options,
+// and transfers the ownership of the instance to the container when the Owned option is passed.
+func RegisterInstance[T any](c *Container, v T, opts ...RegisterOption) error {
+	options := newRegisterOptions(opts)
+	if err := c.RegisterNamedInstance(&v, options.name); err != nil {
+		return err
+	}
+	if options.owned {
+		return c.Own(&v)
+	}
+	return nil
+}
+
+// Register a typed instance on a container.
+//
+// MustRegisterInstance calls RegisterInstance[T](c, v, opts...) and panics if an error is returned.
+func MustRegisterInstance[T any](c *Container, v T, opts ...RegisterOption) {
+	if err := RegisterInstance[T](c, v, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// Decorate the typed instances created by a container.
+//
+// The decorator receives the created instance as T, e.g. Decorate[UserRepository] decorates the UserRepository interface.
+//
+// Decorate calls c.DecorateNamed with the name set by the options.
+func Decorate[T any](c *Container, decorate func(Factory, T) (T, error), opts ...RegisterOption) error {
+	options := newRegisterOptions(opts)
+	var fn func(Factory, interface{}) (interface{}, error)
+	if decorate != nil {
+		fn = func(factory Factory, instance interface{}) (interface{}, error) {
+			v, ok := instance.(T)
+			if !ok {
+				// set v in the same manner as resolving an instance, e.g. Decorate[*V] for a V instance
+				typ := reflect.TypeOf((*T)(nil)).Elem()
+				rv, err := GetNamedInstance(instance, options.name)
+				if err != nil {
+					return nil, err
+				}
+				if rv.Type() != elemType(typ) {
+					return nil, errUnexpectedValueType(rv.Type(), options.name, elemType(typ))
+				}
+				elem, err := newElem(typ, rv, options.name)
+				if err != nil {
+					return nil, err
+				}
+				v = elem.Interface().(T)
+			}
+			decorated, err := decorate(factory, v)
+			if err != nil {
+				return nil, err
+			}
+			return decorated, nil
+		}
+	}
+	return c.DecorateNamed(fn, (*T)(nil), options.name)
+}
+
+// Decorate the typed instances created by a container.
+//
+// MustDecorate calls Decorate[T](c, decorate, opts...) and panics if an error is returned.
+func MustDecorate[T any](c *Container, decorate func(Factory, T) (T, error), opts ...RegisterOption) {
+	if err := Decorate[T](c, decorate, opts...); err != nil {
+		panic(err)
+	}
+}
+
+//-----------------------------------------------
+// factory helpers
+//-----------------------------------------------
+
+// ResolveAs uses a factory to resolve an instance of type T.
+//
+// ResolveAs calls ResolveNamedAs[T](factory, "").
+func ResolveAs[T any](factory Factory) (T, error) {
+	return ResolveNamedAs[T](factory, "")
+}
+
+// MustResolveAs uses a factory to resolve an instance of type T.
+//
+// MustResolveAs calls ResolveAs[T](factory) and panics if an error is returned.
+func MustResolveAs[T any](factory Factory) T {
+	v, err := ResolveAs[T](factory)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ResolveNamedAs uses a factory to resolve a named instance of type T.
+//
+// Returns the zero value of T when an error is returned by factory.ResolveNamed.
+func ResolveNamedAs[T any](factory Factory, name string) (T, error) {
+	var v T
+	if err := factory.ResolveNamed(&v, name); err != nil {
+		var zero T
+		return zero, err
+	}
+	return v, nil
+}
+
+// MustResolveNamedAs uses a factory to resolve a named instance of type T.
+//
+// MustResolveNamedAs calls ResolveNamedAs[T](factory, name) and panics if an error is returned.
+func MustResolveNamedAs[T any](factory Factory, name string) T {
+	v, err := ResolveNamedAs[T](factory, name)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// ResolveAllAs uses a factory to resolve every instance of type T added for a type.
+//
+// ResolveAllAs calls ResolveAllNamedAs[T](factory, "").
+func ResolveAllAs[T any](factory Factory) ([]T, error) {
+	return ResolveAllNamedAs[T](factory, "")
+}
+
+// ResolveAllNamedAs uses a factory to resolve every instance of type T added for a type and name.
+//
+// ResolveAllNamedAs calls ResolveAllNamed(factory, &[]T, name).
+func ResolveAllNamedAs[T any](factory Factory, name string) ([]T, error) {
+	var v []T
+	if err := ResolveAllNamed(factory, &v, name); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ResolveNamedMapAs uses a factory to resolve every named instance of type T.
+//
+// ResolveNamedMapAs calls ResolveNamedMap(factory, &map[string]T).
+func ResolveNamedMapAs[T any](factory Factory) (map[string]T, error) {
+	var v map[string]T
+	if err := ResolveNamedMap(factory, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ResolveOptionalAs uses a factory to resolve an optional named instance of type T.
+//
+// Returns the zero value of T and false when the instance isn't registered or found. (see ResolveOptional)
+func ResolveOptionalAs[T any](factory Factory, name string) (T, bool, error) {
+	var v T
+	found, err := ResolveOptional(factory, &v, name)
+	if !found || err != nil {
+		var zero T
+		return zero, found, err
+	}
+	return v, true, nil
+}
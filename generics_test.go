@@ -0,0 +1,82 @@
+package ioc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// to test
+// Register (MustRegister calls Register)
+// RegisterInstance (MustRegisterInstance calls RegisterInstance)
+// ResolveNamedAs (ResolveAs/MustResolveAs/MustResolveNamedAs calls ResolveNamedAs)
+
+var _ = Describe("Generics", func() {
+	var container *Container
+	BeforeEach(func() { container = NewContainer() })
+
+	It("should register/resolve pointer instances", func() {
+		type V struct{ name string }
+		MustRegister(container, func(Factory) (*V, error) { return &V{name: "test"}, nil })
+		v := MustResolveAs[*V](container)
+		Expect(v).ToNot(BeNil())
+		Expect(v.name).To(Equal("test"))
+		// the registration is compatible with the untyped api
+		var v1 *V
+		container.MustResolve(&v1)
+		Expect(v1.name).To(Equal("test"))
+	})
+	It("should register/resolve interface instances", func() {
+		MustRegister[io.Reader](container, func(Factory) (io.Reader, error) { return strings.NewReader("test"), nil })
+		r := MustResolveAs[io.Reader](container)
+		Expect(r).ToNot(BeNil())
+		_, ok := r.(*strings.Reader)
+		Expect(ok).To(BeTrue())
+	})
+	It("should register/resolve named instances with a lifetime", func() {
+		x := 1
+		MustRegister(container, func(Factory) (int, error) { return x, nil }, WithName("one"), WithLifetime(PerRequest))
+		Expect(MustResolveNamedAs[int](container, "one")).To(Equal(1))
+		x = 2
+		Expect(MustResolveNamedAs[int](container, "one")).To(Equal(2))
+	})
+	It("should register interface instances without a pointer", func() {
+		var r io.Reader = strings.NewReader("test")
+		MustRegisterInstance(container, r, WithName("reader"))
+		v := MustResolveNamedAs[io.Reader](container, "reader")
+		Expect(v).To(Equal(r))
+	})
+	It("should resolve from any Factory", func() {
+		values := NewValues()
+		values.MustSet(1)
+		Expect(MustResolveAs[int](values)).To(Equal(1))
+		MustRegister(container, func(factory Factory) (string, error) {
+			v, err := ResolveAs[int](factory)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprint(v), nil
+		})
+		container.MustRegisterInstance(2)
+		Expect(MustResolveAs[string](container)).To(Equal("2"))
+	})
+	Context("should return an error when", func() {
+		It("instance factory is nil", func() {
+			err := Register[int](container, nil)
+			Expect(err).ToNot(BeNil())
+		})
+		It("instance not registered", func() {
+			v, err := ResolveAs[*testStruct](container)
+			Expect(err).ToNot(BeNil())
+			Expect(v).To(BeNil())
+		})
+		It("instance factory returns an error", func() {
+			MustRegister(container, func(Factory) (int, error) { return 0, fmt.Errorf("Something went wrong") })
+			_, err := ResolveAs[int](container)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+})
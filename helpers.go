@@ -39,3 +39,83 @@ func MustResolveNamed(factory Factory, namedInstances map[string][]interface{})
 		panic(err)
 	}
 }
+
+// ResolveAll uses a factory to resolve every instance added for a type.
+//
+// ResolveAll calls ResolveAllNamed(factory, v, "").
+func ResolveAll(factory Factory, v interface{}) error {
+	return ResolveAllNamed(factory, v, "")
+}
+
+// MustResolveAll uses a factory to resolve every instance added for a type.
+//
+// MustResolveAll calls ResolveAll(factory, v) and panics if an error is returned.
+func MustResolveAll(factory Factory, v interface{}) {
+	if err := ResolveAll(factory, v); err != nil {
+		panic(err)
+	}
+}
+
+// ResolveAllNamed uses a factory to resolve every instance added for a type and name.
+//
+// v must be a pointer to a slice e.g. *[]http.Handler.
+//
+// Returns an error when the factory doesn't implement MultiFactory.
+func ResolveAllNamed(factory Factory, v interface{}, name string) error {
+	multiFactory, ok := factory.(MultiFactory)
+	if !ok {
+		return errUnsupportedFactory(factory, "resolving every instance of a type")
+	}
+	return multiFactory.ResolveAllNamed(v, name)
+}
+
+// MustResolveAllNamed uses a factory to resolve every instance added for a type and name.
+//
+// MustResolveAllNamed calls ResolveAllNamed(factory, v, name) and panics if an error is returned.
+func MustResolveAllNamed(factory Factory, v interface{}, name string) {
+	if err := ResolveAllNamed(factory, v, name); err != nil {
+		panic(err)
+	}
+}
+
+// ResolveNamedMap uses a factory to resolve every named instance of a type.
+//
+// v must be a pointer to a map with a string key type e.g. *map[string]*sql.DB.
+//
+// Returns an error when the factory doesn't implement MapFactory.
+func ResolveNamedMap(factory Factory, v interface{}) error {
+	mapFactory, ok := factory.(MapFactory)
+	if !ok {
+		return errUnsupportedFactory(factory, "resolving every named instance of a type")
+	}
+	return mapFactory.ResolveNamedMap(v)
+}
+
+// MustResolveNamedMap uses a factory to resolve every named instance of a type.
+//
+// MustResolveNamedMap calls ResolveNamedMap(factory, v) and panics if an error is returned.
+func MustResolveNamedMap(factory Factory, v interface{}) {
+	if err := ResolveNamedMap(factory, v); err != nil {
+		panic(err)
+	}
+}
+
+// ResolveOptional uses a factory to resolve an optional named instance by type.
+//
+// Returns false and no error when the instance isn't registered or found.
+// Any other error, e.g. an error returned by an instance factory function, is returned.
+//
+// Factories that don't implement OptionalFactory are called using ResolveNamed, and
+// errors with the ErrUnresolvedDependency or ErrInstanceNotFound error code are treated as not found.
+func ResolveOptional(factory Factory, v interface{}, name string) (bool, error) {
+	if optionalFactory, ok := factory.(OptionalFactory); ok {
+		return optionalFactory.ResolveNamedOptional(v, name)
+	}
+	if err := factory.ResolveNamed(v, name); err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,141 @@
+/*
+Package iochttp integrates the ioc containers with the net/http package.
+
+Middleware creates a scoped container per request, registers the request values on the scope
+and carries the scope in the request context. Handler resolves the parameters of a function from the request scope
+and passes the errors to an error handler. (see WithErrorHandler)
+
+Example:
+	c := ioc.NewContainer()
+	c.MustRegisterConstructor(newPostgresUserRepository, ioc.PerContainer, ioc.As((*UserRepository)(nil)))
+
+	mux := http.NewServeMux()
+	mux.Handle("/users", iochttp.Handler(func(w http.ResponseWriter, r *http.Request, repo UserRepository) error {
+		user, err := repo.GetById(1)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(w).Encode(user)
+	}))
+	http.ListenAndServe(":8080", iochttp.Middleware(c)(mux))
+*/
+package iochttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/shelakel/go-ioc"
+)
+
+// Middleware creates a scoped container per request using the options.
+//
+// The following values are set on the scoped container:
+//	- http.ResponseWriter
+//	- *http.Request (carrying the scoped container in the request context)
+//	- context.Context (the request context)
+//
+// The scoped container is carried by the request context (see ioc.FromContext and ioc.FactoryFromContext)
+// and is closed when the handler returns. Errors returned when the scope is closed are ignored.
+func Middleware(c *ioc.Container, opts ...ioc.ScopeOption) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := c.Scope(opts...)
+			defer scope.Close()
+			ctx := ioc.WithContainer(r.Context(), scope)
+			r = r.WithContext(ctx)
+			scope.MustSet(&w)
+			scope.MustSet(r)
+			scope.MustSet(&ctx)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+var typeError = reflect.TypeOf((*error)(nil)).Elem()
+
+// HandlerOption configures a Handler.
+type HandlerOption func(*handlerOptions)
+
+// handlerOptions are the options of a Handler.
+type handlerOptions struct {
+	onError func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// WithErrorHandler sets the function responding to the request when a parameter can't be resolved
+// or the function returns an error, e.g. to log the error.
+//
+// The default error handler responds with 500 Internal Server Error.
+func WithErrorHandler(onError func(w http.ResponseWriter, r *http.Request, err error)) HandlerOption {
+	return func(o *handlerOptions) {
+		if onError != nil {
+			o.onError = onError
+		}
+	}
+}
+
+// internalServerError responds with 500 Internal Server Error.
+func internalServerError(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// Handler creates a http.Handler calling a function with the parameters resolved from the request scope.
+//
+// The function must return nothing or an error, e.g.
+//	func(w http.ResponseWriter, r *http.Request, repo UserRepository) error
+//
+// The parameters are resolved by type on every call within one resolution, using the request context,
+// i.e. the Per Resolve instances are shared by the parameters. (see Middleware)
+// The error handler is called when a parameter can't be resolved or the function returns an error. (see WithErrorHandler)
+//
+// Handler panics when fn isn't a function with a supported signature.
+func Handler(fn interface{}, opts ...HandlerOption) http.Handler {
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func || fnValue.IsNil() || fnValue.Type().IsVariadic() ||
+		fnValue.Type().NumOut() > 1 || (fnValue.Type().NumOut() == 1 && fnValue.Type().Out(0) != typeError) {
+		panic(fmt.Sprintf("iochttp: Handler: unsupported function \"%T\"; expected a non-nil function returning nothing or an error.", fn))
+	}
+	options := &handlerOptions{onError: internalServerError}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(options)
+		}
+	}
+	fnType := fnValue.Type()
+	params := make([]reflect.Type, fnType.NumIn())
+	for i := range params {
+		params[i] = fnType.In(i)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		args, err := resolveArgs(r.Context(), params)
+		if err != nil {
+			options.onError(w, r, err)
+			return
+		}
+		out := fnValue.Call(args)
+		if len(out) == 1 && !out[0].IsNil() {
+			options.onError(w, r, out[0].Interface().(error))
+		}
+	})
+}
+
+// resolveArgs resolves the parameters within one resolution from the container carried by the context.
+func resolveArgs(ctx context.Context, params []reflect.Type) ([]reflect.Value, error) {
+	args := make([]reflect.Value, len(params))
+	err := ioc.WithResolutionFromContext(ctx, func(factory ioc.Factory) error {
+		for i, param := range params {
+			v := reflect.New(param)
+			if err := factory.ResolveNamed(v.Interface(), ""); err != nil {
+				return err
+			}
+			args[i] = v.Elem()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return args, nil
+}
@@ -0,0 +1,13 @@
+package iochttp
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestIochttp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Iochttp Suite")
+}
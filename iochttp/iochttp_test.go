@@ -0,0 +1,118 @@
+package iochttp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/shelakel/go-ioc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// to test
+// Middleware
+// - request values
+// - scope closed when the handler returns
+// Handler (WithErrorHandler)
+
+type closer struct{ closed *bool }
+
+// unitOfWork is shared by the parameters of one handler call.
+type unitOfWork struct{ id int }
+
+func (c *closer) Close() error {
+	*c.closed = true
+	return nil
+}
+
+var _ = Describe("Middleware", func() {
+	var container *ioc.Container
+	BeforeEach(func() { container = ioc.NewContainer() })
+
+	serve := func(handler http.Handler) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		Middleware(container)(handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w
+	}
+
+	It("should set the request values on the scope carried by the request context", func() {
+		serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope, ok := ioc.FromContext(r.Context())
+			Expect(ok).To(BeTrue())
+			Expect(scope).ToNot(Equal(container))
+			var rw http.ResponseWriter
+			scope.MustResolve(&rw)
+			Expect(rw).To(Equal(w))
+			var req *http.Request
+			scope.MustResolve(&req)
+			Expect(req.URL.Path).To(Equal("/"))
+			var ctx context.Context
+			scope.MustResolve(&ctx)
+			Expect(ctx).To(Equal(r.Context()))
+		}))
+	})
+	It("should close the scope when the handler returns", func() {
+		closed := false
+		container.MustRegister(func(ioc.Factory) (interface{}, error) {
+			return &closer{closed: &closed}, nil
+		}, (*closer)(nil), ioc.PerScope)
+		serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := ioc.ResolveAs[*closer](ioc.FactoryFromContext(r.Context()))
+			Expect(err).To(BeNil())
+			Expect(closed).To(BeFalse())
+		}))
+		Expect(closed).To(BeTrue())
+	})
+	Context("Handler", func() {
+		It("should resolve the function parameters from the request scope", func() {
+			container.MustRegisterInstance("value")
+			w := serve(Handler(func(w http.ResponseWriter, r *http.Request, v string) {
+				io.WriteString(w, r.URL.Path+v)
+			}))
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(Equal("/value"))
+		})
+		It("should respond with an internal server error when a parameter can't be resolved", func() {
+			w := serve(Handler(func(v int) {}))
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+		})
+		It("should respond with an internal server error when the function returns an error", func() {
+			w := serve(Handler(func() error { return fmt.Errorf("Something went wrong") }))
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+		})
+		It("should share the Per Resolve instances between the function parameters", func() {
+			x := 0
+			container.MustRegister(func(ioc.Factory) (interface{}, error) { x++; return &unitOfWork{id: x}, nil }, (*unitOfWork)(nil), ioc.PerResolve)
+			handler := Handler(func(a, b *unitOfWork) {
+				Expect(a.id).To(Equal(b.id))
+			})
+			serve(handler)
+			serve(handler)
+			Expect(x).To(Equal(2))
+		})
+		It("should pass the errors to the error handler", func() {
+			var errs []error
+			onError := WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+				errs = append(errs, err)
+				w.WriteHeader(http.StatusBadRequest)
+			})
+			w := serve(Handler(func(v int) {}, onError))
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			errSomethingWentWrong := fmt.Errorf("Something went wrong")
+			w = serve(Handler(func() error { return errSomethingWentWrong }, onError))
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(errs).To(HaveLen(2))
+			Expect(errs[0]).To(MatchError(ioc.ErrNotFound))
+			Expect(errs[1]).To(Equal(errSomethingWentWrong))
+		})
+		It("should panic when the function signature isn't supported", func() {
+			Expect(func() { Handler(nil) }).To(Panic())
+			Expect(func() { Handler(1) }).To(Panic())
+			Expect(func() { Handler(func() int { return 1 }) }).To(Panic())
+		})
+	})
+})
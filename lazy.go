@@ -0,0 +1,168 @@
+package ioc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// Lazy resolves an instance of type T on the first call to Value.
+//
+// A Lazy[T] is resolved by a Container or a Factory passed to a factory function
+// without resolving T, e.g.
+//	var repo ioc.Lazy[UserRepository]
+//	if err := ioc.Resolve(factory, &repo); err != nil {
+//		return nil, err
+//	}
+//
+// The instance is resolved from the container scope that resolved the Lazy[T].
+// A successfully resolved instance is cached, an error isn't.
+//
+// Lazy is safe for concurrent use and can be copied after it's resolved.
+type Lazy[T any] struct {
+	state *lazyState[T]
+}
+
+// lazyState contains the shared state of a Lazy[T].
+type lazyState[T any] struct {
+	m        *sync.Mutex
+	resolve  func(v interface{}) error
+	value    T
+	resolved bool
+}
+
+// Value resolves the instance on the first call and returns the cached instance on subsequent calls.
+//
+// Returns an error when:
+//	- The Lazy[T] wasn't resolved by a Container or Factory.
+//	- The instance can't be resolved. (see ResolveNamed)
+func (lazy Lazy[T]) Value() (T, error) {
+	if lazy.state == nil {
+		var zero T
+		return zero, errUnresolvedDependency(reflect.TypeOf((*T)(nil)).Elem(), "", nil)
+	}
+	state := lazy.state
+	state.m.Lock()
+	defer state.m.Unlock()
+	if !state.resolved {
+		var v T
+		if err := state.resolve(&v); err != nil {
+			var zero T
+			return zero, err
+		}
+		state.value = v
+		state.resolved = true
+	}
+	return state.value, nil
+}
+
+// MustValue resolves the instance on the first call and returns the cached instance on subsequent calls.
+//
+// MustValue calls Value() and panics if an error is returned.
+func (lazy Lazy[T]) MustValue() T {
+	v, err := lazy.Value()
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// lazyType returns the type of the instance.
+func (lazy *Lazy[T]) lazyType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// initLazy sets the function used to resolve the instance.
+func (lazy *Lazy[T]) initLazy(resolve func(v interface{}) error) {
+	lazy.state = &lazyState[T]{m: new(sync.Mutex), resolve: resolve}
+}
+
+// lazyInitializer is implemented by *Lazy[T].
+type lazyInitializer interface {
+	initLazy(resolve func(v interface{}) error)
+	lazyType() reflect.Type
+}
+
+var typeLazyInitializer = reflect.TypeOf((*lazyInitializer)(nil)).Elem()
+
+//-----------------------------------------------
+// providers
+//-----------------------------------------------
+
+// providedType returns the (non-pointer) type provided by a provider type.
+//
+// Returns nil if the type isn't a provider type. (see provide)
+func providedType(typ reflect.Type) reflect.Type {
+	if reflect.PointerTo(typ).Implements(typeLazyInitializer) {
+		return elemType(reflect.Zero(reflect.PointerTo(typ)).Interface().(lazyInitializer).lazyType())
+	}
+	if typ.Kind() != reflect.Func || typ.NumIn() != 0 {
+		return nil
+	}
+	switch {
+	case typ.NumOut() == 1 && typ.Out(0) != typeError:
+	case typ.NumOut() == 2 && typ.Out(0) != typeError && typ.Out(1) == typeError:
+	default:
+		return nil
+	}
+	return elemType(typ.Out(0))
+}
+
+// provide creates a provider for the type, used to resolve an instance of the provided type on demand.
+//
+// Supported provider types are:
+//	- func() (T, error)
+//	- func() T (panics when T can't be resolved)
+//	- Lazy[T]
+//
+// Returns nil if the type isn't a provider type.
+func (resolver *dependencyResolver) provide(typ reflect.Type, name string) *reflect.Value {
+	resolve := func(v interface{}) error {
+		return resolver.resolveProvided(v, name)
+	}
+	if reflect.PointerTo(typ).Implements(typeLazyInitializer) {
+		lazy := reflect.New(typ)
+		lazy.Interface().(lazyInitializer).initLazy(resolve)
+		instance := lazy.Elem()
+		return &instance
+	}
+	if typ.Kind() != reflect.Func || typ.NumIn() != 0 {
+		return nil
+	}
+	returnsErr := false
+	switch {
+	case typ.NumOut() == 1 && typ.Out(0) != typeError:
+	case typ.NumOut() == 2 && typ.Out(0) != typeError && typ.Out(1) == typeError:
+		returnsErr = true
+	default:
+		return nil
+	}
+	outType := typ.Out(0)
+	instance := reflect.MakeFunc(typ, func([]reflect.Value) []reflect.Value {
+		v := reflect.New(outType)
+		err := resolve(v.Interface())
+		if !returnsErr {
+			if err != nil {
+				panic(err)
+			}
+			return []reflect.Value{v.Elem()}
+		}
+		if err != nil {
+			return []reflect.Value{reflect.Zero(outType), reflect.ValueOf(&err).Elem()}
+		}
+		return []reflect.Value{v.Elem(), reflect.Zero(typeError)}
+	})
+	return &instance
+}
+
+// resolveProvided resolves a named instance by type for a provider.
+//
+// While the resolution that created the provider is in progress, the provider shares the resolution path
+// to detect infinite recursion. Afterwards, every call to the provider is a new resolution.
+func (resolver *dependencyResolver) resolveProvided(v interface{}, name string) error {
+	if resolver.g.finished() {
+		resolver = newDependencyResolver(context.Background(), resolver.c, newDependencyResolverGraph())
+		defer resolver.g.finish()
+	}
+	return resolver.ResolveNamed(v, name)
+}
@@ -0,0 +1,113 @@
+package ioc
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// to test
+// provider types: func() (T, error), func() T, Lazy[T]
+// - resolve on call from the container scope that resolved the provider
+// - break construction cycles
+// - infinite recursion while the resolution is in progress
+
+var _ = Describe("Lazy", func() {
+	var (
+		container *Container
+		count     int
+	)
+	BeforeEach(func() {
+		container = NewContainer()
+		count = 0
+		container.MustRegister(func(Factory) (interface{}, error) {
+			count++
+			return count, nil
+		}, (*int)(nil), PerRequest)
+	})
+
+	It("should resolve a func() (T, error) provider", func() {
+		var provider func() (int, error)
+		container.MustResolve(&provider)
+		Expect(count).To(Equal(0))
+		v, err := provider()
+		Expect(err).To(BeNil())
+		Expect(v).To(Equal(1))
+		v, err = provider()
+		Expect(err).To(BeNil())
+		Expect(v).To(Equal(2))
+	})
+	It("should resolve a func() T provider", func() {
+		var provider func() int
+		container.MustResolve(&provider)
+		Expect(provider()).To(Equal(1))
+		Expect(provider()).To(Equal(2))
+	})
+	It("should resolve a Lazy[T] and cache the instance", func() {
+		var lazy Lazy[int]
+		container.MustResolve(&lazy)
+		Expect(count).To(Equal(0))
+		Expect(lazy.MustValue()).To(Equal(1))
+		Expect(lazy.MustValue()).To(Equal(1))
+		Expect(count).To(Equal(1))
+	})
+	It("should resolve from the container scope that resolved the provider", func() {
+		container.MustRegister(func(Factory) (interface{}, error) { return "root", nil }, (*string)(nil), PerScope)
+		scopedContainer := container.Scope()
+		var provider func() (string, error)
+		scopedContainer.MustResolve(&provider)
+		v, err := provider()
+		Expect(err).To(BeNil())
+		Expect(v).To(Equal("root"))
+		scopedContainer.MustSetNamed("scoped", "name")
+		var named Lazy[string]
+		scopedContainer.MustResolveNamed(&named, "name")
+		Expect(named.MustValue()).To(Equal("scoped"))
+	})
+	It("should break construction cycles", func() {
+		type A struct{ b Lazy[*testStruct] }
+		MustRegister(container, func(factory Factory) (*A, error) {
+			a := &A{}
+			if err := Resolve(factory, &a.b); err != nil {
+				return nil, err
+			}
+			return a, nil
+		})
+		MustRegister(container, func(factory Factory) (*testStruct, error) {
+			if _, err := ResolveAs[*A](factory); err != nil {
+				return nil, err
+			}
+			return &testStruct{name: "b"}, nil
+		})
+		a := MustResolveAs[*A](container)
+		Expect(a.b.MustValue().name).To(Equal("b"))
+	})
+	Context("should return an error when", func() {
+		It("the instance can't be resolved", func() {
+			var provider func() (string, error)
+			container.MustResolve(&provider)
+			_, err := provider()
+			Expect(err).ToNot(BeNil())
+			var lazy Lazy[string]
+			container.MustResolve(&lazy)
+			_, err = lazy.Value()
+			Expect(err).ToNot(BeNil())
+			Expect(func() { lazy.MustValue() }).To(Panic())
+		})
+		It("the Lazy[T] wasn't resolved", func() {
+			var lazy Lazy[string]
+			_, err := lazy.Value()
+			Expect(err).ToNot(BeNil())
+		})
+		It("infinite recursion is detected while the resolution is in progress", func() {
+			MustRegister(container, func(factory Factory) (string, error) {
+				provider, err := ResolveAs[func() (string, error)](factory)
+				if err != nil {
+					return "", err
+				}
+				return provider()
+			})
+			_, err := ResolveAs[string](container)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+})
@@ -0,0 +1,331 @@
+package ioc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// LifetimeManager decides where an instance of a registration is cached and when the instance is reused.
+//
+// The built-in PerContainer, PerScope, PerRequest, Pooled and PerResolve lifetimes are implemented by a LifetimeManager.
+// Custom lifetimes are registered using RegisterLifetime.
+type LifetimeManager interface {
+	// Resolve an instance of a registration requested by a (scoped) container.
+	//
+	// create creates a new instance owned by a container, e.g. scope.Root(). The dependencies of the instance
+	// are resolved from the owner container. The created instance isn't cached or owned. (see ResolveSingleton and Own)
+	//
+	// ctx is the context of the request. (see ResolveNamedContext)
+	Resolve(ctx context.Context, scope *Container, registration *Registration, create func(owner *Container) (*reflect.Value, error)) (*reflect.Value, error)
+}
+
+// customLifetimeStart is the first Lifetime assigned to a custom lifetime.
+const customLifetimeStart Lifetime = 1 << 10
+
+// lifetimes is the thread safe registry of lifetime managers.
+var lifetimes = struct {
+	m        *sync.RWMutex
+	managers map[Lifetime]LifetimeManager
+	names    map[Lifetime]string
+	scopes   map[string]Lifetime
+	next     Lifetime
+}{
+	m: new(sync.RWMutex),
+	managers: map[Lifetime]LifetimeManager{
+		PerContainer: perContainerLifetime{},
+		PerScope:     perScopeLifetime{},
+		PerRequest:   perRequestLifetime{},
+		Pooled:       pooledLifetime{},
+		PerResolve:   perResolveLifetime{},
+	},
+	names:  make(map[Lifetime]string),
+	scopes: make(map[string]Lifetime),
+	next:   customLifetimeStart,
+}
+
+// RegisterLifetime registers a custom lifetime managed by a LifetimeManager.
+//
+// The name is returned by (Lifetime).String.
+// The returned Lifetime is passed to the registration methods, e.g. (*Container).RegisterNamed.
+//
+// Panics when the manager is nil.
+func RegisterLifetime(name string, manager LifetimeManager) Lifetime {
+	if manager == nil {
+		panic("ioc: RegisterLifetime: the lifetime manager is nil.")
+	}
+	lifetimes.m.Lock()
+	defer lifetimes.m.Unlock()
+	lifetime := lifetimes.next
+	lifetimes.next++
+	lifetimes.managers[lifetime] = manager
+	lifetimes.names[lifetime] = name
+	return lifetime
+}
+
+// PerNamedScope returns the lifetime requiring that an instance is only created once per named scope,
+// by caching the instance on the nearest scope with the name. (see ScopeNamed)
+//
+// The dependencies of the instance are resolved from the named scope.
+// Resolving the instance from a container without an ancestor scope with the name returns an error with the ErrScopeNotFound error code.
+// The instance of a registration made on a scoped container is only cached on a named scope at or below the scoped container,
+// i.e. the instance isn't shared with the other scopes of an ancestor named scope.
+//
+// The same Lifetime is returned for a name on every call.
+//
+// Panics when the name is empty.
+func PerNamedScope(name string) Lifetime {
+	if name == "" {
+		panic("ioc: PerNamedScope: the scope name is empty.")
+	}
+	lifetimes.m.Lock()
+	defer lifetimes.m.Unlock()
+	if lifetime, ok := lifetimes.scopes[name]; ok {
+		return lifetime
+	}
+	lifetime := lifetimes.next
+	lifetimes.next++
+	lifetimes.managers[lifetime] = perNamedScopeLifetime{name: name}
+	lifetimes.names[lifetime] = fmt.Sprintf("Per Named Scope Lifetime (%s)", name)
+	lifetimes.scopes[name] = lifetime
+	return lifetime
+}
+
+// Get the lifetime manager of a lifetime.
+//
+// Returns nil when the lifetime isn't supported.
+func getLifetimeManager(lifetime Lifetime) LifetimeManager {
+	lifetimes.m.RLock()
+	manager := lifetimes.managers[lifetime]
+	lifetimes.m.RUnlock()
+	return manager
+}
+
+// Get the name of a custom lifetime.
+func getLifetimeName(lifetime Lifetime) (string, bool) {
+	lifetimes.m.RLock()
+	name, ok := lifetimes.names[lifetime]
+	lifetimes.m.RUnlock()
+	return name, ok
+}
+
+//-----------------------------------------------
+// built-in lifetimes
+//-----------------------------------------------
+
+// perContainerLifetime creates an instance once per root container.
+type perContainerLifetime struct{}
+
+func (perContainerLifetime) Resolve(ctx context.Context, scope *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	// further dependency resolution will occur at the root container scope
+	// i.e. no instances from the scoped container are available
+	return scope.rootOf(registration).ResolveSingleton(ctx, registration, create)
+}
+
+// perScopeLifetime creates an instance once per (scoped) container.
+type perScopeLifetime struct{}
+
+func (perScopeLifetime) Resolve(ctx context.Context, scope *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	return scope.ResolveSingleton(ctx, registration, create)
+}
+
+// perRequestLifetime creates an instance on every request.
+type perRequestLifetime struct{}
+
+func (perRequestLifetime) Resolve(ctx context.Context, scope *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	instance, err := create(scope)
+	if err != nil {
+		return nil, err
+	}
+	if scope.d.ownPerRequest {
+		if err = scope.own(registration, instance); err != nil {
+			return nil, err
+		}
+	}
+	return instance, nil
+}
+
+// perNamedScopeLifetime creates an instance once per named scope.
+type perNamedScopeLifetime struct {
+	name string
+}
+
+func (l perNamedScopeLifetime) Resolve(ctx context.Context, scope *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	for c := scope; c != nil; c = c.parent {
+		if c.tag == l.name {
+			return c.ResolveSingleton(ctx, registration, create)
+		}
+		// the scopes above the scope the registration was made on can't resolve the registration
+		if c == registration.scope {
+			break
+		}
+	}
+	return nil, errScopeNotFound(registration.Type, registration.Name, l.name)
+}
+
+// graphLifetimeManager is implemented by the lifetime managers caching an instance on the resolution graph.
+type graphLifetimeManager interface {
+	resolveGraph(ctx context.Context, g *dependencyResolverGraph, scope *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error)
+}
+
+// perResolveLifetime creates an instance once per resolution.
+//
+// The Per Resolve instances are cached on the dependencyResolverGraph of the resolution and
+// created by the requesting container like Per Request instances.
+type perResolveLifetime struct{}
+
+// Resolve is only called outside a resolution, i.e. a new instance is created on every request.
+func (perResolveLifetime) Resolve(ctx context.Context, scope *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	return perRequestLifetime{}.Resolve(ctx, scope, registration, create)
+}
+
+func (perResolveLifetime) resolveGraph(ctx context.Context, g *dependencyResolverGraph, scope *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	if instance := g.get(registration); instance != nil {
+		return instance, nil
+	}
+	instance, err := perRequestLifetime{}.Resolve(ctx, scope, registration, create)
+	if err != nil {
+		return nil, err
+	}
+	g.set(registration, instance)
+	return instance, nil
+}
+
+//-----------------------------------------------
+// lifetime manager helpers
+//-----------------------------------------------
+
+// Root returns the root container, or the container when it isn't scoped.
+func (c *Container) Root() *Container {
+	if c.root != nil {
+		return c.root
+	}
+	return c
+}
+
+// Get the container caching the Per Container instances of a registration,
+// i.e. the scoped container the registration was made on or the root container.
+func (c *Container) rootOf(registration *Registration) *Container {
+	if registration.scope != nil {
+		return registration.scope
+	}
+	return c.Root()
+}
+
+// Parent returns the container the scoped container was created from, or nil when the container isn't scoped.
+func (c *Container) Parent() *Container {
+	return c.parent
+}
+
+// ResolveSingleton resolves the instance of a registration cached on the container,
+// creating and owning the instance on the first call.
+//
+// ResolveSingleton is used by lifetime managers to cache an instance once per container.
+// Concurrent resolve calls wait on the in-progress construction of the instance and
+// receive the same instance or error. Failed constructions aren't cached.
+//
+// Returns an error when:
+//	- create returns an error.
+//	- create panics while the resolve call waits on the in-progress construction of the instance.
+//	  The panic is propagated to the resolve call constructing the instance.
+//	- The container is closed while the instance is created.
+//	- The context is done while waiting on the in-progress construction of the instance. The error wraps ctx.Err().
+func (c *Container) ResolveSingleton(ctx context.Context, registration *Registration, create func(owner *Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	if instance := c.getSingleton(registration); instance != nil {
+		return instance, nil
+	}
+	return c.construct(ctx, registration, func() (*reflect.Value, error) {
+		return c.createSingleton(registration, create)
+	})
+}
+
+// construct calls fn once for the concurrent resolve calls constructing the instance of a registration on the container.
+//
+// The other resolve calls wait on the in-progress construction and receive the same instance or error,
+// unless the construction waits on a construction of the waiting resolve call. (ErrResolveInfiniteRecursion)
+// When fn panics, the waiting resolve calls receive an error and the panic is propagated.
+func (c *Container) construct(ctx context.Context, registration *Registration, fn func() (*reflect.Value, error)) (*reflect.Value, error) {
+	resolver := resolverFromContext(ctx)
+	var owner *dependencyResolverGraph
+	if resolver != nil {
+		owner = resolver.g
+	}
+	call, ok := c.flight.begin(registration, owner)
+	if !ok {
+		if resolver != nil {
+			// fail instead of waiting on a construction waiting on this resolution
+			if err := resolver.waitFor(call); err != nil {
+				return nil, err
+			}
+			defer resolver.stopWaiting()
+		}
+		select {
+		case <-call.done:
+			return call.instance, call.err
+		case <-ctx.Done():
+			return nil, errContextDone(registration.Type, registration.Name, ctx.Err())
+		}
+	}
+	var (
+		instance *reflect.Value
+		err      error
+	)
+	defer func() {
+		if r := recover(); r != nil {
+			// notify the waiting resolve calls before the panic is propagated
+			c.flight.end(registration, call, nil, errCreateInstance(registration.Type, registration.Name, fmt.Errorf("panic: %v", r)))
+			panic(r)
+		}
+		c.flight.end(registration, call, instance, err)
+	}()
+	instance, err = fn()
+	return instance, err
+}
+
+// create a singleton instance and set the instance on the container.
+func (c *Container) createSingleton(registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	// the instance may have been set after the first check and before the construction began
+	if instance := c.getSingleton(registration); instance != nil {
+		return instance, nil
+	}
+	instance, err := create(c)
+	if err != nil {
+		return nil, err
+	}
+	// the container owns the instances it creates
+	if err = c.own(registration, instance); err != nil {
+		return nil, err
+	}
+	c.setSingleton(registration, instance)
+	return instance, nil
+}
+
+// own transfers the ownership of a created instance to the container.
+func (c *Container) own(registration *Registration, instance *reflect.Value) error {
+	if disposer := disposable(instance); !c.track(disposer) {
+		// the container was closed while the instance was created
+		if err := dispose(context.Background(), disposer); err != nil {
+			return errDispose(err)
+		}
+		return errContainerClosed(registration.Type, registration.Name)
+	}
+	return nil
+}
+
+// Get a singleton instance for a registration from the container.
+func (c *Container) getSingleton(registration *Registration) *reflect.Value {
+	if registration.Multi {
+		return c.multi.get(registration)
+	}
+	return c.instances.get(registration.Type, registration.Name)
+}
+
+// Set a singleton instance for a registration on the container.
+func (c *Container) setSingleton(registration *Registration, instance *reflect.Value) {
+	if registration.Multi {
+		c.multi.set(registration, instance)
+		return
+	}
+	c.instances.set(registration.Type, registration.Name, instance)
+}
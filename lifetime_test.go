@@ -0,0 +1,326 @@
+package ioc
+
+import (
+	"context"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// to test
+// RegisterLifetime
+// custom LifetimeManager (ResolveSingleton, Root, Parent)
+// Pooled (Resetter)
+// PerContainerTTL (WithClock, ServeStale)
+// PerResolve
+// PerNamedScope (ScopeNamed)
+
+// perParentLifetime caches an instance on the parent of the requesting scope.
+type perParentLifetime struct{}
+
+func (perParentLifetime) Resolve(ctx context.Context, scope *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	if parent := scope.Parent(); parent != nil {
+		scope = parent
+	}
+	return scope.ResolveSingleton(ctx, registration, create)
+}
+
+// pooled is implemented by pooledBuffer.
+type pooled interface {
+	Resetter
+	Write(b []byte)
+}
+
+// pooledBuffer counts the calls to Reset.
+type pooledBuffer struct {
+	data   []byte
+	resets int
+}
+
+func (b *pooledBuffer) Write(data []byte) { b.data = append(b.data, data...) }
+
+func (b *pooledBuffer) Reset() {
+	b.data = b.data[:0]
+	b.resets++
+}
+
+// ttlResource is implemented by ttlToken.
+type ttlResource interface {
+	ID() int
+}
+
+// ttlToken records the call to Close.
+type ttlToken struct {
+	id     int
+	closed atomic.Bool
+}
+
+func (t *ttlToken) ID() int { return t.id }
+
+func (t *ttlToken) Close() error {
+	t.closed.Store(true)
+	return nil
+}
+
+// fakeClock is a clock advanced by tests.
+type fakeClock struct {
+	m   sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.m.Lock()
+	c.now = c.now.Add(d)
+	c.m.Unlock()
+}
+
+// unitOfWork is shared by the repositories of one resolution.
+type unitOfWork struct{ id int }
+
+// uowRepositories depends on two unitOfWork instances.
+type uowRepositories struct{ a, b *unitOfWork }
+
+var perParent = RegisterLifetime("Per Parent Lifetime", perParentLifetime{})
+
+var _ = Describe("Lifetime", func() {
+	var container *Container
+	BeforeEach(func() { container = NewContainer() })
+
+	It("should resolve using a custom lifetime manager", func() {
+		x := 0
+		container.MustRegister(func(Factory) (interface{}, error) { x++; return x, nil }, (*int)(nil), perParent)
+		parent := container.Scope()
+		Expect(MustResolveAs[int](parent.Scope())).To(Equal(1))
+		Expect(MustResolveAs[int](parent.Scope())).To(Equal(1))
+		Expect(MustResolveAs[int](parent)).To(Equal(2))
+		Expect(MustResolveAs[int](container.Scope().Scope())).To(Equal(3))
+		Expect(perParent.String()).To(Equal("Per Parent Lifetime"))
+	})
+	It("should return the root container", func() {
+		scope := container.Scope().Scope()
+		Expect(scope.Root()).To(BeIdenticalTo(container))
+		Expect(container.Root()).To(BeIdenticalTo(container))
+		Expect(container.Parent()).To(BeNil())
+	})
+	It("should panic when the lifetime manager is nil", func() {
+		Expect(func() { RegisterLifetime("nil", nil) }).To(Panic())
+	})
+	Context("ttl", func() {
+		var (
+			clock   *fakeClock
+			created []*ttlToken
+			m       sync.Mutex
+		)
+		BeforeEach(func() {
+			clock = &fakeClock{now: time.Unix(0, 0)}
+			created = nil
+		})
+		register := func(lifetime Lifetime) {
+			container.MustRegister(func(Factory) (interface{}, error) {
+				m.Lock()
+				defer m.Unlock()
+				token := &ttlToken{id: len(created) + 1}
+				created = append(created, token)
+				return token, nil
+			}, (*ttlResource)(nil), lifetime)
+		}
+		resolve := func(c *Container) int {
+			return MustResolveAs[ttlResource](c).ID()
+		}
+		It("should re-create an expired instance", func() {
+			register(PerContainerTTL(time.Minute, WithClock(clock.Now)))
+			scope := container.Scope()
+			Expect(resolve(scope)).To(Equal(1))
+			clock.Advance(59 * time.Second)
+			Expect(resolve(container)).To(Equal(1))
+			clock.Advance(time.Second)
+			Expect(resolve(scope)).To(Equal(2))
+			Expect(resolve(container)).To(Equal(2))
+			Expect(created[0].closed.Load()).To(BeTrue())
+			Expect(created[1].closed.Load()).To(BeFalse())
+			Expect(container.Close()).To(Succeed())
+			Expect(created[1].closed.Load()).To(BeTrue())
+		})
+		It("should serve the expired instance while the instance is re-created", func() {
+			register(PerContainerTTL(time.Minute, WithClock(clock.Now), ServeStale()))
+			Expect(resolve(container)).To(Equal(1))
+			clock.Advance(time.Minute)
+			Expect(resolve(container)).To(Equal(1))
+			Eventually(func() int { return resolve(container) }).Should(Equal(2))
+			m.Lock()
+			defer m.Unlock()
+			Expect(created).To(HaveLen(2))
+			Expect(created[0].closed.Load()).To(BeTrue())
+		})
+		It("should keep the expired instance when the re-creation panics", func() {
+			var calls int32
+			container.MustRegister(func(Factory) (interface{}, error) {
+				id := atomic.AddInt32(&calls, 1)
+				if id == 2 {
+					panic("Something went wrong")
+				}
+				return &ttlToken{id: int(id)}, nil
+			}, (*ttlResource)(nil), PerContainerTTL(time.Minute, WithClock(clock.Now), ServeStale()))
+			Expect(resolve(container)).To(Equal(1))
+			clock.Advance(time.Minute)
+			Expect(resolve(container)).To(Equal(1))
+			Eventually(func() int32 { return atomic.LoadInt32(&calls) }).Should(Equal(int32(2)))
+			Eventually(func() int { return resolve(container) }).Should(Equal(3))
+		})
+		It("should re-create the expired instance after the request is cancelled", func() {
+			container.MustRegister(func(Factory) (interface{}, error) { return "dependency", nil }, (*string)(nil), PerRequest)
+			release := make(chan struct{})
+			errs := make(chan error, 2)
+			id := 0
+			container.MustRegister(func(factory Factory) (interface{}, error) {
+				id++
+				if id > 1 {
+					// the request is cancelled while the instance is re-created
+					<-release
+				}
+				var s string
+				err := Resolve(factory, &s)
+				errs <- err
+				return &ttlToken{id: id}, err
+			}, (*ttlResource)(nil), PerContainerTTL(time.Minute, WithClock(clock.Now), ServeStale()))
+			ctx, cancel := context.WithCancel(context.Background())
+			var v ttlResource
+			container.MustResolveNamedContext(ctx, &v, "")
+			Expect(<-errs).To(BeNil())
+			clock.Advance(time.Minute)
+			container.MustResolveNamedContext(ctx, &v, "")
+			Expect(v.ID()).To(Equal(1))
+			cancel()
+			close(release)
+			Eventually(errs).Should(Receive(BeNil()))
+			Eventually(func() int { return resolve(container) }).Should(Equal(2))
+		})
+		It("should name the lifetime and panic when the duration isn't positive", func() {
+			Expect(PerContainerTTL(time.Second).String()).To(Equal("Per Container TTL Lifetime (1s)"))
+			Expect(func() { PerContainerTTL(0) }).To(Panic())
+		})
+	})
+	It("should share a Per Resolve instance within one resolution", func() {
+		x := 0
+		container.MustRegister(func(Factory) (interface{}, error) { x++; return &unitOfWork{id: x}, nil }, (*unitOfWork)(nil), PerResolve)
+		container.MustRegister(func(factory Factory) (interface{}, error) {
+			a, err := ResolveAs[*unitOfWork](factory)
+			if err != nil {
+				return nil, err
+			}
+			b, err := ResolveAs[*unitOfWork](factory)
+			if err != nil {
+				return nil, err
+			}
+			return &uowRepositories{a: a, b: b}, nil
+		}, (*uowRepositories)(nil), PerRequest)
+		scope := container.Scope()
+		defer scope.Close()
+		repos := MustResolveAs[*uowRepositories](scope)
+		Expect(repos.a.id).To(Equal(1))
+		Expect(repos.b.id).To(Equal(1))
+		repos = MustResolveAs[*uowRepositories](scope)
+		Expect(repos.a.id).To(Equal(2))
+		Expect(repos.b.id).To(Equal(2))
+		Expect(MustResolveAs[*unitOfWork](scope).id).To(Equal(3))
+		Expect(PerResolve.String()).To(Equal("Per Resolve Lifetime"))
+	})
+	Context("named scopes", func() {
+		It("should cache the instance on the nearest named scope", func() {
+			x := 0
+			tenant := PerNamedScope("tenant")
+			Expect(PerNamedScope("tenant")).To(Equal(tenant))
+			Expect(tenant.String()).To(Equal("Per Named Scope Lifetime (tenant)"))
+			container.MustRegister(func(Factory) (interface{}, error) { x++; return x, nil }, (*int)(nil), tenant)
+			tenant1 := container.ScopeNamed("tenant")
+			Expect(tenant1.ScopeName()).To(Equal("tenant"))
+			Expect(MustResolveAs[int](tenant1.Scope().Scope())).To(Equal(1))
+			Expect(MustResolveAs[int](tenant1.Scope())).To(Equal(1))
+			Expect(MustResolveAs[int](tenant1)).To(Equal(1))
+			tenant2 := container.Scope().ScopeNamed("tenant")
+			Expect(MustResolveAs[int](tenant2.Scope())).To(Equal(2))
+			Expect(MustResolveAs[int](tenant2.ScopeNamed("tenant"))).To(Equal(3))
+		})
+		It("should return an error when no ancestor scope has the name", func() {
+			container.MustRegister(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerNamedScope("tenant"))
+			_, err := ResolveAs[int](container.ScopeNamed("job").Scope())
+			Expect(err).To(MatchError(ErrScopeNotFound))
+			Expect(err.Error()).To(ContainSubstring(`no scope named "tenant" found`))
+			Expect(func() { PerNamedScope("") }).To(Panic())
+		})
+		It("should not cache the instance of a scoped registration above the scope", func() {
+			tenant := container.ScopeNamed("tenant")
+			tenant.MustRegister(func(Factory) (interface{}, error) { return 1, nil }, (*int)(nil), PerNamedScope("tenant"))
+			req1 := tenant.Scope()
+			req2 := tenant.Scope()
+			req1.MustRegister(func(Factory) (interface{}, error) { return 2, nil }, (*int)(nil), PerNamedScope("tenant"))
+			_, err := ResolveAs[int](req1)
+			Expect(err).To(MatchError(ErrScopeNotFound))
+			Expect(MustResolveAs[int](req2)).To(Equal(1))
+			Expect(MustResolveAs[int](req1.ScopeNamed("tenant"))).To(Equal(2))
+			Expect(MustResolveAs[int](tenant)).To(Equal(1))
+		})
+	})
+	Context("pooled", func() {
+		var gcPercent int
+		// the pool drops idle instances on garbage collection
+		BeforeEach(func() { gcPercent = debug.SetGCPercent(-1) })
+		AfterEach(func() { debug.SetGCPercent(gcPercent) })
+
+		It("should reuse an instance returned to the pool", func() {
+			created := 0
+			container.MustRegister(func(Factory) (interface{}, error) {
+				created++
+				return &pooledBuffer{}, nil
+			}, (*pooled)(nil), Pooled)
+			scope := container.Scope()
+			b1 := MustResolveAs[pooled](scope).(*pooledBuffer)
+			b2 := MustResolveAs[pooled](scope).(*pooledBuffer)
+			Expect(b1).ToNot(BeIdenticalTo(b2))
+			Expect(created).To(Equal(2))
+			b1.Write([]byte("x"))
+			Expect(scope.Close()).To(Succeed())
+			Expect(b1.resets).To(Equal(1))
+			Expect(b1.data).To(BeEmpty())
+			Expect(b2.resets).To(Equal(1))
+			scope = container.Scope()
+			defer scope.Close()
+			b3 := MustResolveAs[pooled](scope).(*pooledBuffer)
+			Expect(b3 == b1 || b3 == b2).To(BeTrue())
+			Expect(created).To(Equal(2))
+			Expect(Pooled.String()).To(Equal("Pooled Lifetime"))
+		})
+		It("should not track the instances requested from the root container", func() {
+			created := 0
+			container.MustRegister(func(Factory) (interface{}, error) {
+				created++
+				return &pooledBuffer{}, nil
+			}, (*pooled)(nil), Pooled)
+			b1 := MustResolveAs[pooled](container).(*pooledBuffer)
+			b2 := MustResolveAs[pooled](container).(*pooledBuffer)
+			Expect(b1).ToNot(BeIdenticalTo(b2))
+			Expect(created).To(Equal(2))
+			Expect(container.d.instances).To(BeEmpty())
+			Expect(container.Close()).To(Succeed())
+			Expect(b1.resets).To(Equal(0))
+		})
+		It("should return an error when the scope is closed", func() {
+			container.MustRegister(func(Factory) (interface{}, error) { return &pooledBuffer{}, nil }, (*pooled)(nil), Pooled)
+			scope := container.Scope()
+			Expect(scope.Close()).To(Succeed())
+			_, err := ResolveAs[pooled](scope)
+			Expect(err).To(MatchError(ErrContainerClosed))
+		})
+	})
+})
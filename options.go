@@ -0,0 +1,66 @@
+package ioc
+
+//-----------------------------------------------
+// registration options
+//-----------------------------------------------
+
+// RegisterOption configures a registration made by RegisterConstructor or the generic registration functions.
+type RegisterOption func(*registerOptions)
+
+// registerOptions contains the configured registration options.
+type registerOptions struct {
+	name     string
+	lifetime Lifetime
+	implType interface{}
+	owned    bool
+	// lifetimeSet is true when the lifetime was set using WithLifetime.
+	lifetimeSet bool
+}
+
+// newRegisterOptions applies the options to the default registration options.
+//
+// The default name is "" and the default lifetime is PerContainer.
+func newRegisterOptions(opts []RegisterOption) *registerOptions {
+	options := &registerOptions{lifetime: PerContainer}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(options)
+		}
+	}
+	return options
+}
+
+// WithName sets the name of the registration.
+func WithName(name string) RegisterOption {
+	return func(options *registerOptions) {
+		options.name = name
+	}
+}
+
+// WithLifetime sets the lifetime of the registration.
+//
+// WithLifetime is ignored when registering an instance and isn't supported when registering a constructor.
+func WithLifetime(lifetime Lifetime) RegisterOption {
+	return func(options *registerOptions) {
+		options.lifetime = lifetime
+		options.lifetimeSet = true
+	}
+}
+
+// As sets the implementing type of a constructor registration, e.g. As((*UserRepository)(nil)).
+//
+// As is ignored by the generic registration functions.
+func As(implType interface{}) RegisterOption {
+	return func(options *registerOptions) {
+		options.implType = implType
+	}
+}
+
+// Owned transfers the ownership of a registered instance to the container, to dispose the instance when the container is closed.
+//
+// Owned is ignored when registering an instance factory, because the container owns the instances it creates.
+func Owned() RegisterOption {
+	return func(options *registerOptions) {
+		options.owned = true
+	}
+}
@@ -0,0 +1,95 @@
+package ioc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// Resetter is implemented by pooled instances to reset the instance state
+// before the instance is returned to the pool. (see Pooled)
+type Resetter interface {
+	Reset()
+}
+
+// pools contains the instance pools of the Pooled registrations by registration id.
+type pools struct {
+	m     *sync.Mutex
+	pools map[uint64]*sync.Pool
+}
+
+// newPools creates a new pools.
+func newPools() *pools {
+	return &pools{m: new(sync.Mutex), pools: make(map[uint64]*sync.Pool)}
+}
+
+// Get the pool of a registration.
+func (p *pools) get(registration *Registration) *sync.Pool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	pool, ok := p.pools[registration.id]
+	if !ok {
+		pool = new(sync.Pool)
+		p.pools[registration.id] = pool
+	}
+	return pool
+}
+
+// pooledInstance returns an instance to the pool when the scope owning the instance is closed.
+type pooledInstance struct {
+	pool     *sync.Pool
+	instance *reflect.Value
+}
+
+// Dispose resets the instance when the instance implements Resetter and returns the instance to the pool.
+func (p *pooledInstance) Dispose(context.Context) error {
+	candidates := make([]reflect.Value, 0, 2)
+	if p.instance.CanAddr() {
+		candidates = append(candidates, p.instance.Addr())
+	}
+	candidates = append(candidates, *p.instance)
+	for _, candidate := range candidates {
+		if !candidate.CanInterface() {
+			continue
+		}
+		if resetter, ok := candidate.Interface().(Resetter); ok {
+			resetter.Reset()
+			break
+		}
+	}
+	p.pool.Put(p.instance)
+	return nil
+}
+
+// pooledLifetime hands out an instance from a pool per registration on every request.
+//
+// The instances are created by the root container, i.e. the dependencies are resolved from the root container,
+// because a pooled instance is reused across scopes. The instances of a registration made on a scoped container
+// are created by and pooled on the scoped container. An instance is returned to the pool when the requesting scoped container is closed.
+//
+// An instance requested from the root container isn't returned to the pool, like a Per Request instance,
+// because the root container is only closed at shutdown.
+//
+// Pooled instances aren't disposed; the pool may drop an idle instance at any time. (see sync.Pool)
+type pooledLifetime struct{}
+
+func (pooledLifetime) Resolve(ctx context.Context, scope *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	root := scope.rootOf(registration)
+	pool := root.pools.get(registration)
+	instance, _ := pool.Get().(*reflect.Value)
+	if instance == nil {
+		var err error
+		if instance, err = create(root); err != nil {
+			return nil, err
+		}
+	}
+	if scope.parent == nil {
+		// the root container would keep every instance until closed
+		return instance, nil
+	}
+	if !scope.track(&pooledInstance{pool: pool, instance: instance}) {
+		pool.Put(instance)
+		return nil, errContainerClosed(registration.Type, registration.Name)
+	}
+	return instance, nil
+}
@@ -0,0 +1,203 @@
+package ioc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// PopulateOption configures Populate.
+type PopulateOption func(*populateOptions)
+
+// populateOptions contains the configured Populate options.
+type populateOptions struct {
+	unexported bool
+}
+
+// IncludeUnexported enables the population of unexported tagged fields.
+func IncludeUnexported() PopulateOption {
+	return func(options *populateOptions) {
+		options.unexported = true
+	}
+}
+
+// populateTag represents a parsed `ioc:"name[,optional]"` or `ioc_<source>:"name[,optional]"` struct tag.
+type populateTag struct {
+	source   string // the name of the Factory used to resolve the field, "" for the current Factory
+	name     string
+	optional bool
+}
+
+// parsePopulateTag parses the value of an ioc or ioc_<source> struct tag.
+func parsePopulateTag(source, value string) populateTag {
+	parts := strings.Split(value, ",")
+	tag := populateTag{source: source, name: parts[0]}
+	for _, option := range parts[1:] {
+		if strings.TrimSpace(option) == "optional" {
+			tag.optional = true
+		}
+	}
+	return tag
+}
+
+// lookupPopulateTags returns the ioc and ioc_<source> tags of a struct field.
+//
+// lookupPopulateTags follows the struct tag conventions of reflect.StructTag.Get.
+func lookupPopulateTags(tag reflect.StructTag) []populateTag {
+	var tags []populateTag
+	for tag != "" {
+		// skip leading space
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+		// scan to colon
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := string(tag[:i])
+		tag = tag[i+1:]
+		// scan quoted string to find value
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		quoted := string(tag[:i+1])
+		tag = tag[i+1:]
+		value, err := strconv.Unquote(quoted)
+		if err != nil || value == "-" {
+			continue
+		}
+		if key == "ioc" {
+			tags = append(tags, parsePopulateTag("", value))
+		} else if strings.HasPrefix(key, "ioc_") && len(key) > len("ioc_") {
+			tags = append(tags, parsePopulateTag(key[len("ioc_"):], value))
+		}
+	}
+	return tags
+}
+
+// Populate uses a factory to set the tagged fields of a struct.
+//
+// Fields are resolved by type and the name in the `ioc` struct tag, e.g.
+//	type Handler struct {
+//		Repository UserRepository `ioc:""`         // resolve by type
+//		DB         *sql.DB        `ioc:"primary"`  // resolve by type and name
+//		Log        Logger         `ioc:",optional"` // leave unset when not registered
+//		ignored    string
+//	}
+//
+// Fields tagged `ioc_<source>:"name"` are resolved dynamically by resolving the Factory named <source>
+// from the factory and calling ResolveNamed(field, name) on it, e.g.
+//	type UserHandler struct {
+//		ID    string `ioc_route:"id"`            // factory.ResolveNamed(&route, "route") -> route.ResolveNamed(&ID, "id")
+//		Query string `ioc_query:"q,optional"`
+//	}
+//
+// Fields without an ioc tag, or tagged `ioc:"-"`, are ignored.
+// Unexported fields are ignored unless the IncludeUnexported option is passed.
+//
+// Returns an error when:
+//	- The target type is nil.
+//	- The target isn't a pointer to a struct.
+//	- A field has more than one ioc or ioc_<source> tag.
+//	- A required field can't be resolved or an optional field failed to resolve.
+//	  Every failed field is reported in one error. (see (*Error).Inner)
+func Populate(factory Factory, target interface{}, opts ...PopulateOption) error {
+	options := &populateOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(options)
+		}
+	}
+	rv, err := GetNamedSetter(target, "")
+	if err != nil {
+		return err
+	}
+	typ := rv.Type()
+	if typ.Kind() != reflect.Struct {
+		return errRequireStruct(typ)
+	}
+	var errs []error
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tags := lookupPopulateTags(field.Tag)
+		if len(tags) == 0 {
+			continue
+		}
+		if !field.IsExported() && !options.unexported {
+			continue
+		}
+		if len(tags) > 1 {
+			errs = append(errs, fmt.Errorf("field \"%s\": multiple ioc tags", field.Name))
+			continue
+		}
+		if err := populateField(factory, rv.Field(i), tags[0]); err != nil {
+			errs = append(errs, fmt.Errorf("field \"%s\": %w", field.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errPopulate(typ, errors.Join(errs...))
+	}
+	return nil
+}
+
+// MustPopulate uses a factory to set the tagged fields of a struct.
+//
+// MustPopulate calls Populate(factory, target, opts...) and panics if an error is returned.
+func MustPopulate(factory Factory, target interface{}, opts ...PopulateOption) {
+	if err := Populate(factory, target, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// populateField resolves an instance and sets the field.
+//
+// The field is only set when the instance is resolved.
+func populateField(factory Factory, field reflect.Value, tag populateTag) error {
+	if tag.source != "" {
+		var source Factory
+		if found, err := populateResolve(factory, &source, tag.source, tag.optional); !found || err != nil {
+			return err
+		}
+		factory = source
+	}
+	instance := reflect.New(field.Type())
+	if found, err := populateResolve(factory, instance.Interface(), tag.name, tag.optional); !found || err != nil {
+		return err
+	}
+	if !field.CanSet() {
+		// unexported field
+		field = reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+	}
+	field.Set(instance.Elem())
+	return nil
+}
+
+// populateResolve resolves a named instance by type, using ResolveOptional for optional instances.
+func populateResolve(factory Factory, v interface{}, name string, optional bool) (bool, error) {
+	if optional {
+		return ResolveOptional(factory, v, name)
+	}
+	if err := factory.ResolveNamed(v, name); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,144 @@
+package ioc
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// to test
+// Populate (MustPopulate calls Populate)
+// - required, named, optional and ignored fields
+// - unexported fields (IncludeUnexported)
+// - dynamic population (ioc_<source> tags)
+// - aggregated errors
+
+var _ = Describe("Populate", func() {
+	var container *Container
+	BeforeEach(func() {
+		container = NewContainer()
+		container.MustRegisterInstance(1)
+		container.MustRegisterNamedInstance("primary", "db")
+	})
+
+	It("should set the tagged fields", func() {
+		var target struct {
+			Count   int    `ioc:""`
+			DB      string `ioc:"db"`
+			Ignored int
+			Skipped int `ioc:"-"`
+		}
+		MustPopulate(container, &target)
+		Expect(target.Count).To(Equal(1))
+		Expect(target.DB).To(Equal("primary"))
+		Expect(target.Ignored).To(Equal(0))
+		Expect(target.Skipped).To(Equal(0))
+	})
+	It("should leave optional fields unset when not registered", func() {
+		var target struct {
+			Count int     `ioc:""`
+			Rate  float64 `ioc:",optional"`
+		}
+		MustPopulate(container, &target)
+		Expect(target.Count).To(Equal(1))
+		Expect(target.Rate).To(Equal(0.0))
+	})
+	It("should only set unexported fields when included", func() {
+		var target struct {
+			count int `ioc:""`
+		}
+		MustPopulate(container, &target)
+		Expect(target.count).To(Equal(0))
+		MustPopulate(container, &target, IncludeUnexported())
+		Expect(target.count).To(Equal(1))
+	})
+	It("should populate using any Factory", func() {
+		values := NewValues()
+		values.MustSet(2)
+		var target struct {
+			Count int `ioc:""`
+		}
+		MustPopulate(values, &target)
+		Expect(target.Count).To(Equal(2))
+	})
+	Context("dynamic population", func() {
+		BeforeEach(func() {
+			route := NewValues()
+			route.MustSetNamed("42", "id")
+			var factory Factory = route
+			container.MustSetNamed(&factory, "route")
+		})
+		It("should set fields using the named source Factory", func() {
+			var target struct {
+				Count int    `ioc:""`
+				ID    string `ioc_route:"id"`
+			}
+			MustPopulate(container, &target)
+			Expect(target.Count).To(Equal(1))
+			Expect(target.ID).To(Equal("42"))
+		})
+		It("should set fields using a scoped container", func() {
+			route := NewValues()
+			route.MustSetNamed("7", "id")
+			var factory Factory = route
+			scope := container.Scope()
+			scope.MustSetNamed(&factory, "route")
+			var target struct {
+				ID string `ioc_route:"id"`
+			}
+			MustPopulate(scope, &target)
+			Expect(target.ID).To(Equal("7"))
+		})
+		It("should leave optional fields unset when not found", func() {
+			var target struct {
+				Name  string `ioc_route:"name,optional"`
+				Query string `ioc_query:"q,optional"`
+			}
+			MustPopulate(container, &target)
+			Expect(target.Name).To(Equal(""))
+			Expect(target.Query).To(Equal(""))
+		})
+		It("should return an error when the source Factory isn't registered", func() {
+			var target struct {
+				Query string `ioc_query:"q"`
+			}
+			Expect(Populate(container, &target)).ToNot(BeNil())
+		})
+		It("should return an error when a field has multiple ioc tags", func() {
+			var target struct {
+				ID string `ioc:"" ioc_route:"id"`
+			}
+			Expect(Populate(container, &target)).ToNot(BeNil())
+		})
+	})
+	Context("should return an error when", func() {
+		It("target isn't a pointer to a struct", func() {
+			v := 1
+			Expect(Populate(container, &v)).ToNot(BeNil())
+			Expect(Populate(container, struct{}{})).ToNot(BeNil())
+		})
+		It("fields can't be resolved", func() {
+			var target struct {
+				Rate float64 `ioc:""`
+				Name string  `ioc:"missing"`
+			}
+			err := Populate(container, &target)
+			Expect(err).ToNot(BeNil())
+			e, ok := err.(*Error)
+			Expect(ok).To(BeTrue())
+			Expect(e.Code).To(Equal(ErrPopulate))
+			Expect(e.Error()).To(ContainSubstring("\"Rate\""))
+			Expect(e.Error()).To(ContainSubstring("\"Name\""))
+		})
+		It("an optional field failed to resolve", func() {
+			container.MustRegister(func(Factory) (interface{}, error) {
+				return nil, fmt.Errorf("Something went wrong")
+			}, (*float64)(nil), PerRequest)
+			var target struct {
+				Rate float64 `ioc:",optional"`
+			}
+			Expect(Populate(container, &target)).ToNot(BeNil())
+		})
+	})
+})
@@ -82,3 +82,17 @@ func GetNamedType(v interface{}, name string) (reflect.Type, error) {
 	}
 	return typ.Elem(), nil
 }
+
+// Create a new value of type typ set to the instance.
+//
+// newElem sets the value in the same manner as GetNamedSetter to support pointer types,
+// and is used to set slice and map elements.
+func newElem(typ reflect.Type, instance *reflect.Value, name string) (reflect.Value, error) {
+	elem := reflect.New(typ)
+	elemSetter, err := GetNamedSetter(elem.Interface(), name)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	elemSetter.Set(*instance)
+	return elem.Elem(), nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
 // Lifetime represents the lifetime characteristics of an instance.
@@ -16,6 +17,13 @@ const (
 	PerScope
 	// Per Request lifetime requires that a new instance is created on every request.
 	PerRequest
+	// Pooled lifetime hands out an instance from a pool on every request,
+	// and returns the instance to the pool when the requesting scoped container is closed.
+	// An instance requested from the root container isn't returned to the pool.
+	Pooled
+	// Per Resolve lifetime requires that an instance is only created once per resolution,
+	// i.e. the instance is shared by the dependencies of one top-level resolve call.
+	PerResolve
 )
 
 func (lifetime Lifetime) String() string {
@@ -26,7 +34,14 @@ func (lifetime Lifetime) String() string {
 		return "Per Scope Lifetime"
 	case PerRequest:
 		return "Per Request Lifetime"
+	case Pooled:
+		return "Pooled Lifetime"
+	case PerResolve:
+		return "Per Resolve Lifetime"
 	default:
+		if name, ok := getLifetimeName(lifetime); ok {
+			return name
+		}
 		return fmt.Sprintf("%+v", int(lifetime))
 	}
 }
@@ -36,9 +51,17 @@ func (lifetime Lifetime) String() string {
 //-----------------------------------------------
 
 // registry is a thread safe type-name-registration container.
+//
+// A scoped registry overlays the registry of the parent container:
+// registrations that aren't found on the scoped registry are looked up on the parent registry.
 type registry struct {
 	m             *sync.RWMutex
 	registrations map[reflect.Type]map[string]*Registration
+	multi         map[reflect.Type]map[string][]*Registration
+	decorators    map[reflect.Type]map[string][]func(Factory, interface{}) (interface{}, error)
+	// lastID is shared by the scoped registries, because instances are cached by registration id.
+	lastID *uint64
+	parent *registry
 }
 
 // newRegistry creates a new registry.
@@ -46,10 +69,57 @@ func newRegistry() *registry {
 	return &registry{
 		m:             new(sync.RWMutex),
 		registrations: make(map[reflect.Type]map[string]*Registration),
+		multi:         make(map[reflect.Type]map[string][]*Registration),
+		decorators:    make(map[reflect.Type]map[string][]func(Factory, interface{}) (interface{}, error)),
+		lastID:        new(uint64),
+	}
+}
+
+// newRegistryScope creates a new scoped registry overlaying the parent registry.
+//
+// The maps of a scoped registry are created on the first registration, because most scopes don't register.
+func newRegistryScope(parent *registry) *registry {
+	return &registry{
+		m:      new(sync.RWMutex),
+		lastID: parent.lastID,
+		parent: parent,
+	}
+}
+
+// prepare assigns an id and the decorators to a registration before it's stored.
+//
+// must be called with the write lock held.
+func (r *registry) prepare(typ reflect.Type, name string, registration *Registration) {
+	registration.id = atomic.AddUint64(r.lastID, 1)
+	registration.Decorators = r.getDecorators(typ, name)
+}
+
+// Get the decorators by type and name, including the decorators of the parent registry.
+//
+// must be called with the lock held.
+func (r *registry) getDecorators(typ reflect.Type, name string) []func(Factory, interface{}) (interface{}, error) {
+	decorators := r.decorators[typ][name]
+	if r.parent == nil {
+		return decorators
+	}
+	r.parent.m.RLock()
+	inherited := r.parent.getDecorators(typ, name)
+	r.parent.m.RUnlock()
+	if len(inherited) == 0 {
+		return decorators
 	}
+	if len(decorators) == 0 {
+		return inherited
+	}
+	all := make([]func(Factory, interface{}) (interface{}, error), 0, len(inherited)+len(decorators))
+	all = append(all, inherited...)
+	return append(all, decorators...)
 }
 
 // Get a registration by type and name.
+//
+// Returns the last added multi registration when no registration is set for the type and name.
+// The parent registry is checked when the type and name aren't registered on the scoped registry.
 func (r *registry) get(typ reflect.Type, name string) *Registration {
 	// assume typ != nil
 	r.m.RLock()
@@ -57,14 +127,103 @@ func (r *registry) get(typ reflect.Type, name string) *Registration {
 	if named, ok := r.registrations[typ]; ok {
 		registration = named[name]
 	}
+	if registration == nil {
+		if registrations := r.multi[typ][name]; len(registrations) > 0 {
+			registration = registrations[len(registrations)-1]
+		}
+	}
 	r.m.RUnlock()
+	if registration == nil && r.parent != nil {
+		return r.parent.get(typ, name)
+	}
 	return registration
 }
 
+// Get the multi registrations by type and name in the order they were added.
+//
+// The multi registrations of the parent registry are returned first.
+func (r *registry) getMulti(typ reflect.Type, name string) []*Registration {
+	// assume typ != nil
+	var inherited []*Registration
+	if r.parent != nil {
+		inherited = r.parent.getMulti(typ, name)
+	}
+	r.m.RLock()
+	// the returned slice isn't modified by add, because add only appends
+	registrations := r.multi[typ][name]
+	r.m.RUnlock()
+	if len(inherited) == 0 {
+		return registrations
+	}
+	if len(registrations) == 0 {
+		return inherited
+	}
+	all := make([]*Registration, 0, len(inherited)+len(registrations))
+	all = append(all, inherited...)
+	return append(all, registrations...)
+}
+
+// Get the names of the registrations and multi registrations by type, including the names on the parent registry.
+func (r *registry) names(typ reflect.Type) []string {
+	// assume typ != nil
+	r.m.RLock()
+	names := make([]string, 0, len(r.registrations[typ])+len(r.multi[typ]))
+	for name := range r.registrations[typ] {
+		names = append(names, name)
+	}
+	for name := range r.multi[typ] {
+		names = appendNames(names, name)
+	}
+	r.m.RUnlock()
+	if r.parent != nil {
+		names = appendNames(names, r.parent.names(typ)...)
+	}
+	return names
+}
+
+// Add the names of every registration by type, including the registrations on the parent registry.
+func (r *registry) typeNames(names typeNames) {
+	r.m.RLock()
+	for typ, named := range r.registrations {
+		for name := range named {
+			names.add(typ, name)
+		}
+	}
+	for typ, named := range r.multi {
+		for name := range named {
+			names.add(typ, name)
+		}
+	}
+	r.m.RUnlock()
+	if r.parent != nil {
+		r.parent.typeNames(names)
+	}
+}
+
+// Add a multi registration by type and name.
+func (r *registry) add(typ reflect.Type, name string, registration *Registration) {
+	// assume typ != nil
+	r.m.Lock()
+	r.prepare(typ, name, registration)
+	if r.multi == nil {
+		r.multi = make(map[reflect.Type]map[string][]*Registration)
+	}
+	if named, ok := r.multi[typ]; ok {
+		named[name] = append(named[name], registration)
+	} else {
+		r.multi[typ] = map[string][]*Registration{name: {registration}}
+	}
+	r.m.Unlock()
+}
+
 // Add or update a registration by type and name.
 func (r *registry) set(typ reflect.Type, name string, registration *Registration) {
 	// assume typ != nil
 	r.m.Lock()
+	r.prepare(typ, name, registration)
+	if r.registrations == nil {
+		r.registrations = make(map[reflect.Type]map[string]*Registration)
+	}
 	if named, ok := r.registrations[typ]; ok {
 		named[name] = registration
 	} else {
@@ -73,8 +232,52 @@ func (r *registry) set(typ reflect.Type, name string, registration *Registration
 	r.m.Unlock()
 }
 
-// Get all the registrations.
+// Add a decorator by type and name.
+//
+// The registrations by type and name are replaced by copies with the updated decorators,
+// because registrations aren't modified after they're stored.
+//
+// The decorators of a scoped registry only apply to the registrations on the scoped registry.
+func (r *registry) decorate(typ reflect.Type, name string, decorator func(Factory, interface{}) (interface{}, error)) {
+	// assume typ != nil
+	r.m.Lock()
+	if r.decorators == nil {
+		r.decorators = make(map[reflect.Type]map[string][]func(Factory, interface{}) (interface{}, error))
+	}
+	current := r.decorators[typ][name]
+	decorators := make([]func(Factory, interface{}) (interface{}, error), len(current), len(current)+1)
+	copy(decorators, current)
+	decorators = append(decorators, decorator)
+	if named, ok := r.decorators[typ]; ok {
+		named[name] = decorators
+	} else {
+		r.decorators[typ] = map[string][]func(Factory, interface{}) (interface{}, error){name: decorators}
+	}
+	decorators = r.getDecorators(typ, name)
+	if registration := r.registrations[typ][name]; registration != nil {
+		decorated := *registration
+		decorated.Decorators = decorators
+		r.registrations[typ][name] = &decorated
+	}
+	if multi := r.multi[typ][name]; len(multi) > 0 {
+		registrations := make([]*Registration, len(multi))
+		for i, registration := range multi {
+			decorated := *registration
+			decorated.Decorators = decorators
+			registrations[i] = &decorated
+		}
+		r.multi[typ][name] = registrations
+	}
+	r.m.Unlock()
+}
+
+// Get all the registrations, including the registrations on the parent registry
+// that aren't replaced by a registration on the scoped registry.
 func (r *registry) getAll() []*Registration {
+	var inherited []*Registration
+	if r.parent != nil {
+		inherited = r.parent.getAll()
+	}
 	r.m.RLock()
 	registrations := make([]*Registration, 0)
 	for _, named := range r.registrations {
@@ -82,10 +285,66 @@ func (r *registry) getAll() []*Registration {
 			registrations = append(registrations, registration)
 		}
 	}
+	for _, named := range r.multi {
+		for _, multi := range named {
+			registrations = append(registrations, multi...)
+		}
+	}
+	for _, registration := range inherited {
+		if registration.Multi || r.registrations[registration.Type][registration.Name] == nil {
+			registrations = append(registrations, registration)
+		}
+	}
 	r.m.RUnlock()
 	return registrations
 }
 
+// clear discards the registrations and decorators of a scoped registry.
+func (r *registry) clear() {
+	r.m.Lock()
+	r.registrations = nil
+	r.multi = nil
+	r.decorators = nil
+	r.m.Unlock()
+}
+
+//-----------------------------------------------
+// registration values
+//-----------------------------------------------
+
+// registrationValues is a thread safe registration-instance container.
+//
+// registrationValues stores the singleton instances of multi registrations,
+// which can't be stored by type and name.
+//
+// Instances are stored by registration id, which is retained when a registration is decorated.
+type registrationValues struct {
+	m         *sync.RWMutex
+	instances map[uint64]*reflect.Value
+}
+
+// newRegistrationValues creates a new registrationValues.
+func newRegistrationValues() *registrationValues {
+	return &registrationValues{new(sync.RWMutex), make(map[uint64]*reflect.Value)}
+}
+
+// Get an instance by registration.
+//
+// Returns nil if the instance wasn't found.
+func (values *registrationValues) get(registration *Registration) *reflect.Value {
+	values.m.RLock()
+	instance := values.instances[registration.id]
+	values.m.RUnlock()
+	return instance
+}
+
+// Add or update an instance by registration.
+func (values *registrationValues) set(registration *Registration, instance *reflect.Value) {
+	values.m.Lock()
+	values.instances[registration.id] = instance
+	values.m.Unlock()
+}
+
 //-----------------------------------------------
 // registration
 //-----------------------------------------------
@@ -97,12 +356,22 @@ type Registration struct {
 	Value            interface{}
 	CreateInstanceFn func(Factory) (interface{}, error)
 	Lifetime         Lifetime
+	// Dependencies contains the types resolved by the factory function, when known.
+	Dependencies []reflect.Type
+	// Multi is true when the registration was added as one of many registrations for the type and name.
+	Multi bool
+	// Decorators wrap the instance created by the factory function, in the order they were added.
+	Decorators []func(Factory, interface{}) (interface{}, error)
+	id         uint64
+	// scope is the scoped container the registration was made on, or nil for a registration on the root container.
+	scope *Container
 }
 
-// CreateInstance creates an instance using the factory function.
+// CreateInstance creates an instance using the factory function and applies the decorators.
 //
 // Returns an error when:
 //	- The factory function is nil or returns an error. (Registration.CreateInstanceFn)
+//	- A decorator returns an error.
 //	- The created instance type is nil. (no type information)
 //	- The created instance is a nil pointer or interface.
 //	- The created instance type doesn't match the registration type or
@@ -115,6 +384,11 @@ func (r *Registration) CreateInstance(factory Factory) (*reflect.Value, error) {
 	if err != nil {
 		return nil, errCreateInstance(r.Type, r.Name, err)
 	}
+	for _, decorate := range r.Decorators {
+		if instance, err = decorate(factory, instance); err != nil {
+			return nil, errCreateInstance(r.Type, r.Name, err)
+		}
+	}
 	rv, err := GetNamedInstance(instance, r.Name)
 	if err != nil {
 		return nil, err
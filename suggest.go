@@ -0,0 +1,65 @@
+package ioc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Suggestion is a near match for an instance that can't be resolved by type and name.
+type Suggestion struct {
+	Type reflect.Type
+	Name string
+	// Reason describes why the instance is a near match.
+	Reason string
+}
+
+// String returns the type, name and reason of the suggestion, e.g. ioc.Repository("primary") (registered under another name)
+func (suggestion Suggestion) String() string {
+	if suggestion.Name != "" {
+		return fmt.Sprintf("%s(%q) (%s)", suggestion.Type, suggestion.Name, suggestion.Reason)
+	}
+	return fmt.Sprintf("%s (%s)", suggestion.Type, suggestion.Reason)
+}
+
+// typeNames contains the names of the instances that can be resolved by type.
+type typeNames map[reflect.Type][]string
+
+// add the names for a type.
+func (names typeNames) add(typ reflect.Type, name ...string) {
+	names[typ] = appendNames(names[typ], name...)
+}
+
+// suggest the near matches for a named instance by type that can't be resolved:
+//	- The same type under another name.
+//	- A type implementing the interface type. (e.g. registered *Impl or called values.Set(f) instead of values.Set(&f))
+//	- A type with the same name in another package.
+func suggest(typ reflect.Type, name string, candidates typeNames) []Suggestion {
+	var suggestions []Suggestion
+	for candidate, names := range candidates {
+		var reason string
+		switch {
+		case candidate == typ:
+			reason = "registered under another name"
+		case typ.Kind() == reflect.Interface && candidate.Implements(typ):
+			reason = fmt.Sprintf("implements %s", typ)
+		case typ.Kind() == reflect.Interface && reflect.PointerTo(candidate).Implements(typ):
+			candidate = reflect.PointerTo(candidate)
+			reason = fmt.Sprintf("implements %s", typ)
+		case candidate.String() == typ.String():
+			reason = fmt.Sprintf("same name in package %q", candidate.PkgPath())
+		default:
+			continue
+		}
+		for _, candidateName := range names {
+			if candidate == typ && candidateName == name {
+				continue
+			}
+			suggestions = append(suggestions, Suggestion{Type: candidate, Name: candidateName, Reason: reason})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].String() < suggestions[j].String()
+	})
+	return suggestions
+}
@@ -0,0 +1,189 @@
+package ioc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// TTLOption configures a time-bounded lifetime. (see PerContainerTTL)
+type TTLOption func(*ttlLifetime)
+
+// WithClock sets the function returning the current time, e.g. to control the expiry of instances in tests.
+//
+// The default clock is time.Now.
+func WithClock(now func() time.Time) TTLOption {
+	return func(l *ttlLifetime) {
+		if now != nil {
+			l.now = now
+		}
+	}
+}
+
+// ServeStale serves an expired instance while the instance is re-created in the background.
+//
+// Only one goroutine re-creates an expired instance. The expired instance is kept when the re-creation fails
+// or the factory function panics, and the instance is re-created on the next request.
+func ServeStale() TTLOption {
+	return func(l *ttlLifetime) {
+		l.serveStale = true
+	}
+}
+
+// PerContainerTTL registers a lifetime requiring that an instance is created once per container,
+// and re-created on the first request after the instance was created longer than d ago.
+//
+// Replaced instances implementing Disposer or io.Closer are disposed. Errors returned when a replaced instance is disposed are ignored.
+//
+// The background re-creation (see ServeStale) uses the values of the context of the request that found the instance expired,
+// but isn't cancelled with the request.
+//
+// Every call registers a new lifetime, i.e. PerContainerTTL should be called once per duration and the Lifetime reused.
+//
+// Panics when d isn't positive.
+func PerContainerTTL(d time.Duration, opts ...TTLOption) Lifetime {
+	if d <= 0 {
+		panic("ioc: PerContainerTTL: the duration must be positive.")
+	}
+	l := &ttlLifetime{ttl: d, now: time.Now}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(l)
+		}
+	}
+	return RegisterLifetime(fmt.Sprintf("Per Container TTL Lifetime (%s)", d), l)
+}
+
+// expiry is the expiry of a time-bounded instance.
+type expiry struct {
+	at         time.Time
+	refreshing bool
+}
+
+// expiries contains the expiry of the time-bounded instances of a container by registration id.
+type expiries struct {
+	m  *sync.Mutex
+	at map[uint64]*expiry
+}
+
+// newExpiries creates a new expiries.
+func newExpiries() *expiries {
+	return &expiries{m: new(sync.Mutex), at: make(map[uint64]*expiry)}
+}
+
+// Returns true when the instance of a registration expired at or before now.
+func (e *expiries) expired(registration *Registration, now time.Time) bool {
+	e.m.Lock()
+	defer e.m.Unlock()
+	x, ok := e.at[registration.id]
+	return !ok || !now.Before(x.at)
+}
+
+// Set the expiry of the instance of a registration.
+func (e *expiries) set(registration *Registration, at time.Time) {
+	e.m.Lock()
+	e.at[registration.id] = &expiry{at: at}
+	e.m.Unlock()
+}
+
+// beginRefresh marks the instance of a registration as being re-created.
+//
+// Returns false when the instance is already being re-created.
+func (e *expiries) beginRefresh(registration *Registration) bool {
+	e.m.Lock()
+	defer e.m.Unlock()
+	x, ok := e.at[registration.id]
+	if !ok || x.refreshing {
+		return false
+	}
+	x.refreshing = true
+	return true
+}
+
+// endRefresh marks the instance of a registration as no longer being re-created.
+func (e *expiries) endRefresh(registration *Registration) {
+	e.m.Lock()
+	if x, ok := e.at[registration.id]; ok {
+		x.refreshing = false
+	}
+	e.m.Unlock()
+}
+
+// ttlLifetime creates an instance once per root container and re-creates the instance once expired.
+type ttlLifetime struct {
+	ttl        time.Duration
+	now        func() time.Time
+	serveStale bool
+}
+
+func (l *ttlLifetime) Resolve(ctx context.Context, scope *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	// further dependency resolution will occur at the root container scope
+	root := scope.rootOf(registration)
+	if instance := root.getSingleton(registration); instance != nil {
+		if !root.expiries.expired(registration, l.now()) {
+			return instance, nil
+		}
+		if l.serveStale {
+			if root.expiries.beginRefresh(registration) {
+				go func() {
+					defer root.expiries.endRefresh(registration)
+					// a panic fails the re-creation like an error, i.e. the expired instance is kept
+					defer func() { _ = recover() }()
+					ctx, create := detach(ctx, registration, create)
+					_, _ = l.refresh(ctx, root, registration, create)
+				}()
+			}
+			return instance, nil
+		}
+	}
+	return l.refresh(ctx, root, registration, create)
+}
+
+// refresh (re-)creates the instance of a registration once, while concurrent requests wait on the in-progress construction.
+func (l *ttlLifetime) refresh(ctx context.Context, root *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	return root.construct(ctx, registration, func() (*reflect.Value, error) {
+		return l.replace(root, registration, create)
+	})
+}
+
+// detach the context and create function of a request from the request, to re-create an instance in the background.
+//
+// The dependencies of the instance are resolved by a new resolution that isn't cancelled with the request.
+func detach(ctx context.Context, registration *Registration, create func(*Container) (*reflect.Value, error)) (context.Context, func(*Container) (*reflect.Value, error)) {
+	resolver := resolverFromContext(ctx)
+	if resolver == nil {
+		return context.WithoutCancel(ctx), create
+	}
+	detached := resolver.detach()
+	return &resolveContext{Context: detached.ctx, resolver: detached}, func(owner *Container) (*reflect.Value, error) {
+		// the background re-creation is a resolution by itself
+		defer detached.g.finish()
+		return detached.creator(registration)(owner)
+	}
+}
+
+// replace the expired instance of a registration on the root container and dispose the replaced instance.
+func (l *ttlLifetime) replace(root *Container, registration *Registration, create func(*Container) (*reflect.Value, error)) (*reflect.Value, error) {
+	replaced := root.getSingleton(registration)
+	// the instance may have been re-created after the expiry was checked
+	if replaced != nil && !root.expiries.expired(registration, l.now()) {
+		return replaced, nil
+	}
+	instance, err := create(root)
+	if err != nil {
+		return nil, err
+	}
+	if err = root.own(registration, instance); err != nil {
+		return nil, err
+	}
+	root.setSingleton(registration, instance)
+	root.expiries.set(registration, l.now().Add(l.ttl))
+	if replaced != nil {
+		if disposer := disposable(replaced); root.d.untrack(disposer) {
+			_ = dispose(context.Background(), disposer)
+		}
+	}
+	return instance, nil
+}
@@ -62,6 +62,73 @@ func (values *Values) getParent(typ reflect.Type, name string) *reflect.Value {
 	return instance
 }
 
+// Get the names of the instances by type, including the names of the instances on the ancestors.
+func (values *Values) names(typ reflect.Type) []string {
+	// assume typ != nil
+	var names []string
+	for current := values; current != nil; current = current.parent {
+		current.m.RLock()
+		for name := range current.instances[typ] {
+			names = appendNames(names, name)
+		}
+		current.m.RUnlock()
+	}
+	return names
+}
+
+// Get the names of the instances by type, excluding the names of the instances on the ancestors.
+func (values *Values) localNames(typ reflect.Type) []string {
+	// assume typ != nil
+	values.m.RLock()
+	names := make([]string, 0, len(values.instances[typ]))
+	for name := range values.instances[typ] {
+		names = append(names, name)
+	}
+	values.m.RUnlock()
+	return names
+}
+
+// Add the names of every instance by type, excluding the instances of the ancestors.
+func (values *Values) localTypeNames(names typeNames) {
+	values.m.RLock()
+	for typ, named := range values.instances {
+		for name := range named {
+			names.add(typ, name)
+		}
+	}
+	values.m.RUnlock()
+}
+
+// Add the names of every instance by type, including the instances of the ancestors.
+func (values *Values) typeNames(names typeNames) {
+	for current := values; current != nil; current = current.parent {
+		current.m.RLock()
+		for typ, named := range current.instances {
+			for name := range named {
+				names.add(typ, name)
+			}
+		}
+		current.m.RUnlock()
+	}
+}
+
+// appendNames appends the names not already contained in names.
+func appendNames(names []string, other ...string) []string {
+	for _, name := range other {
+		found := false
+		for _, existing := range names {
+			if existing == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // Add or update an instance by type and name.
 func (values *Values) set(typ reflect.Type, name string, instance *reflect.Value) {
 	// assume typ != nil and instance != nil
@@ -112,7 +179,9 @@ func (values *Values) GetNamed(v interface{}, name string) error {
 	instance := values.get(typ, name)
 	if instance == nil {
 		if instance = values.getParent(typ, name); instance == nil {
-			return errInstanceNotFound(typ, name)
+			candidates := make(typeNames)
+			values.typeNames(candidates)
+			return errInstanceNotFound(typ, name, suggest(typ, name, candidates))
 		}
 	}
 	instanceSetter.Set(*instance)
@@ -180,3 +249,63 @@ func (values *Values) MustSetNamed(v interface{}, name string) {
 func (values *Values) ResolveNamed(v interface{}, name string) error {
 	return values.GetNamed(v, name)
 }
+
+// Resolve an optional named instance by type.
+//
+// Returns false and no error when the instance isn't found.
+//
+// Returns an error when:
+//	- The value type is nil. (v was passed as nil with no type information)
+//	- The value isn't a pointer. (required to set v to the instance)
+//	- The value is a nil pointer which can't be set. (use a pointer to a (nil) pointer instead)
+func (values *Values) ResolveNamedOptional(v interface{}, name string) (bool, error) {
+	instanceSetter, err := GetNamedSetter(v, name)
+	if err != nil {
+		return false, err
+	}
+	typ := instanceSetter.Type()
+	instance := values.get(typ, name)
+	if instance == nil {
+		if instance = values.getParent(typ, name); instance == nil {
+			return false, nil
+		}
+	}
+	instanceSetter.Set(*instance)
+	return true, nil
+}
+
+// Resolve every named instance of a type.
+//
+// v must be a pointer to a map with a string key type, e.g. *map[string]*sql.DB.
+// The map is set to every instance of the map element type, including the instances on the ancestors.
+//
+// Returns an error when:
+//	- The value type is nil.
+//	- The value isn't a pointer to a map with a string key type.
+func (values *Values) ResolveNamedMap(v interface{}) error {
+	instanceSetter, err := GetNamedSetter(v, "")
+	if err != nil {
+		return err
+	}
+	mapType := instanceSetter.Type()
+	if mapType.Kind() != reflect.Map || mapType.Key().Kind() != reflect.String {
+		return errRequireMap(mapType)
+	}
+	elemTyp := mapType.Elem()
+	typ := elemType(elemTyp)
+	names := values.names(typ)
+	instances := reflect.MakeMapWithSize(mapType, len(names))
+	for _, name := range names {
+		instance := values.get(typ, name)
+		if instance == nil {
+			instance = values.getParent(typ, name)
+		}
+		elem, err := newElem(elemTyp, instance, name)
+		if err != nil {
+			return err
+		}
+		instances.SetMapIndex(reflect.ValueOf(name).Convert(mapType.Key()), elem)
+	}
+	instanceSetter.Set(instances)
+	return nil
+}
@@ -89,6 +89,15 @@ var _ = Describe("Values", func() {
 		values.MustGetNamed(&v, "")
 		Expect(v).To(Equal(1))
 	})
+	It("should resolve every named value including ancestors", func() {
+		values.MustSetNamed(1, "one")
+		values.MustSetNamed(2, "two")
+		scopedValues := NewValuesScope(values)
+		scopedValues.MustSetNamed(3, "two")
+		var v map[string]int
+		Expect(ResolveNamedMap(scopedValues, &v)).To(BeNil())
+		Expect(v).To(Equal(map[string]int{"one": 1, "two": 3}))
+	})
 	It("should return instance not found", func() {
 		scopedValues := NewValuesScope(values)
 		scopedValues.MustSetNamed(1, "")